@@ -0,0 +1,206 @@
+// Package appendalias defines an Analyzer that reports appends to a reslice of
+// a variable that is used again afterwards.
+//
+// The slices lesson removes an element with
+//
+//	twelfthSlice := append(ninthSlice[:2], ninthSlice[3:]...)
+//
+// append has enough capacity to work in place, so it writes into the array
+// behind ninthSlice, and the next fmt.Println(ninthSlice) shows a duplicated
+// last element. Assigning the result back to the same variable, as in
+// s = append(s[:i], s[i+1:]...), is the usual idiom and isn't reported.
+package appendalias
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+
+	"github.com/raproid/go-training/analyzers/internal/edit"
+)
+
+const Doc = `report appends to a reslice of a variable that is read again afterwards
+
+append(s[:i], ...) reuses the array behind s when it has the capacity, so it
+overwrites elements that s still shows. Append to a copy instead, e.g.
+slices.Delete(slices.Clone(s), i, j), or assign the result back to s.`
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "appendalias",
+	Doc:      Doc,
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	// every use of a variable, in source order, and which of them only overwrite it
+	uses := map[types.Object][]*ast.Ident{}
+	for id, obj := range pass.TypesInfo.Uses {
+		if _, ok := obj.(*types.Var); ok {
+			uses[obj] = append(uses[obj], id)
+		}
+	}
+	for _, ids := range uses {
+		sort.Slice(ids, func(i, j int) bool { return ids[i].Pos() < ids[j].Pos() })
+	}
+	overwrites := map[*ast.Ident]bool{}
+	inspect.Preorder([]ast.Node{(*ast.AssignStmt)(nil)}, func(n ast.Node) {
+		as := n.(*ast.AssignStmt)
+		if as.Tok != token.ASSIGN && as.Tok != token.DEFINE {
+			return
+		}
+		for _, lhs := range as.Lhs {
+			if id, ok := lhs.(*ast.Ident); ok {
+				overwrites[id] = true
+			}
+		}
+	})
+
+	var file *ast.File
+	inspect.WithStack([]ast.Node{(*ast.File)(nil), (*ast.CallExpr)(nil)}, func(n ast.Node, push bool, stack []ast.Node) bool {
+		if !push {
+			return true
+		}
+		if f, ok := n.(*ast.File); ok {
+			file = f
+			return true
+		}
+		call := n.(*ast.CallExpr)
+		if !isAppend(pass, call) || len(call.Args) == 0 {
+			return true
+		}
+		slice, ok := ast.Unparen(call.Args[0]).(*ast.SliceExpr)
+		if !ok || slice.Slice3 {
+			return true // a full slice expression s[i:j:j] caps the capacity, so append has to copy
+		}
+		id, ok := ast.Unparen(slice.X).(*ast.Ident)
+		if !ok {
+			return true
+		}
+		obj, ok := pass.TypesInfo.Uses[id].(*types.Var)
+		if !ok || !sharesArray(obj.Type()) {
+			return true
+		}
+		if assignedTo(pass, stack, call, obj) {
+			return true // s = append(s[:i], ...) replaces s, so nobody sees the old contents
+		}
+
+		stmtEnd := enclosingStmt(stack).End()
+		var later *ast.Ident
+		for _, u := range uses[obj] {
+			if u.Pos() < stmtEnd {
+				continue
+			}
+			if !overwrites[u] {
+				later = u
+			}
+			break
+		}
+		if later == nil {
+			return true
+		}
+
+		pass.Report(analysis.Diagnostic{
+			Pos: call.Pos(),
+			End: call.End(),
+			Message: fmt.Sprintf("append to %s may overwrite the elements of %s, which is used again at line %d; append to a copy instead",
+				edit.Render(pass.Fset, slice), id.Name, pass.Fset.Position(later.Pos()).Line),
+			SuggestedFixes: fixes(pass, file, call, slice),
+		})
+		return true
+	})
+	return nil, nil
+}
+
+// fixes suggests slices.Delete on a clone for the s[:i], s[j:]... removal
+// idiom and appending to a clone for everything else.
+func fixes(pass *analysis.Pass, file *ast.File, call *ast.CallExpr, slice *ast.SliceExpr) []analysis.SuggestedFix {
+	pkg, imports := edit.Import(file, "slices")
+	x := edit.Render(pass.Fset, slice.X)
+	whole := x
+	if _, ok := pass.TypesInfo.TypeOf(slice.X).Underlying().(*types.Slice); !ok {
+		whole += "[:]" // slices.Clone takes a slice, not an array or a pointer to one
+	}
+
+	var fixes []analysis.SuggestedFix
+	if slice.Low == nil && slice.High != nil && len(call.Args) == 2 && call.Ellipsis.IsValid() {
+		if rest, ok := ast.Unparen(call.Args[1]).(*ast.SliceExpr); ok && rest.High == nil && rest.Low != nil &&
+			!rest.Slice3 && edit.Render(pass.Fset, rest.X) == x {
+			text := fmt.Sprintf("%s.Delete(%s.Clone(%s), %s, %s)", pkg, pkg, whole,
+				edit.Render(pass.Fset, slice.High), edit.Render(pass.Fset, rest.Low))
+			fixes = append(fixes, analysis.SuggestedFix{
+				Message:   "Delete from a copy with slices.Delete",
+				TextEdits: append(imports, analysis.TextEdit{Pos: call.Pos(), End: call.End(), NewText: []byte(text)}),
+			})
+		}
+	}
+	fixes = append(fixes, analysis.SuggestedFix{
+		Message: "Append to a copy",
+		TextEdits: append(imports, analysis.TextEdit{
+			Pos:     slice.Pos(),
+			End:     slice.End(),
+			NewText: []byte(fmt.Sprintf("%s.Clone(%s)", pkg, edit.Render(pass.Fset, slice))),
+		}),
+	})
+	return fixes
+}
+
+func isAppend(pass *analysis.Pass, call *ast.CallExpr) bool {
+	id, ok := ast.Unparen(call.Fun).(*ast.Ident)
+	if !ok {
+		return false
+	}
+	b, ok := pass.TypesInfo.Uses[id].(*types.Builtin)
+	return ok && b.Name() == "append"
+}
+
+// sharesArray reports whether reslicing a value of type t shares its memory:
+// slices, arrays (addressable variables) and pointers to arrays.
+func sharesArray(t types.Type) bool {
+	switch t := t.Underlying().(type) {
+	case *types.Slice, *types.Array:
+		return true
+	case *types.Pointer:
+		_, ok := t.Elem().Underlying().(*types.Array)
+		return ok
+	}
+	return false
+}
+
+// assignedTo reports whether call is the right-hand side of an assignment to obj.
+func assignedTo(pass *analysis.Pass, stack []ast.Node, call *ast.CallExpr, obj types.Object) bool {
+	if len(stack) < 2 {
+		return false
+	}
+	as, ok := stack[len(stack)-2].(*ast.AssignStmt)
+	if !ok {
+		return false
+	}
+	for i, rhs := range as.Rhs {
+		if ast.Unparen(rhs) != call || i >= len(as.Lhs) {
+			continue
+		}
+		if id, ok := as.Lhs[i].(*ast.Ident); ok && pass.TypesInfo.ObjectOf(id) == obj {
+			return true
+		}
+	}
+	return false
+}
+
+// enclosingStmt returns the innermost statement on the stack.
+func enclosingStmt(stack []ast.Node) ast.Node {
+	for i := len(stack) - 1; i >= 0; i-- {
+		if s, ok := stack[i].(ast.Stmt); ok {
+			return s
+		}
+	}
+	return stack[len(stack)-1]
+}
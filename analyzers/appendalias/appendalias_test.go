@@ -0,0 +1,13 @@
+package appendalias_test
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+
+	"github.com/raproid/go-training/analyzers/appendalias"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.RunWithSuggestedFixes(t, analysistest.TestData(), appendalias.Analyzer, "a")
+}
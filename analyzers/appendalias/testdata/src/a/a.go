@@ -0,0 +1,50 @@
+package a
+
+import (
+	"fmt"
+)
+
+func remove() {
+	ninthSlice := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	twelfthSlice := append(ninthSlice[:2], ninthSlice[3:]...) // want `append to ninthSlice\[:2\] may overwrite the elements of ninthSlice, which is used again at line 11; append to a copy instead`
+	fmt.Println(twelfthSlice)
+	fmt.Println(ninthSlice)
+}
+
+func extend(s []int) []int {
+	t := append(s[:1], 42) // want `append to s\[:1\] may overwrite the elements of s, which is used again at line 16`
+	fmt.Println(s)
+	return t
+}
+
+func array() {
+	var a [4]int
+	b := append(a[:2], 1) // want `append to a\[:2\] may overwrite the elements of a`
+	fmt.Println(a, b)
+}
+
+func idiom(s []int, i int) []int {
+	s = append(s[:i], s[i+1:]...)
+	return s
+}
+
+func capped(s []int) {
+	t := append(s[:1:1], 42)
+	fmt.Println(s, t)
+}
+
+func unused(s []int) []int {
+	return append(s[:1], 42)
+}
+
+func overwritten(s []int) {
+	t := append(s[:1], 42)
+	s = nil
+	fmt.Println(s, t)
+}
+
+func deleteFromArray() {
+	var a [5]int
+	b := append(a[:1], a[2:]...) // want `append to a\[:1\] may overwrite the elements of a`
+	fmt.Println(a, b)
+}
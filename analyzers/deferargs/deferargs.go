@@ -0,0 +1,192 @@
+// Package deferargs defines an Analyzer that explains when deferred calls
+// don't see the values their arguments end up with.
+//
+// The defer lesson has
+//
+//	ac := "start"
+//	defer fmt.Println(ac)
+//	ac = "end"
+//
+// which prints "start": the arguments of a deferred call are evaluated when
+// the defer statement runs, not when the call does. The analyzer reports such
+// variables together with the value the call will use, and defers inside
+// loops, which pile up until the function returns.
+package deferargs
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+
+	"github.com/raproid/go-training/analyzers/internal/edit"
+)
+
+const Doc = `report deferred calls whose arguments change later, and defers in loops
+
+The arguments (and the receiver) of a deferred call are evaluated when the
+defer statement runs. Assigning to an argument variable afterwards doesn't
+change what the deferred call gets; wrap the call in a function literal,
+defer func() { ... }(), to use the final value. A defer inside a loop runs
+when the function returns, not at the end of the iteration.`
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "deferargs",
+	Doc:      Doc,
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+// assignment is a write to a variable.
+type assignment struct {
+	pos   token.Pos
+	value ast.Expr // nil for ++, --, op= and multi-value assignments
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	writes := map[types.Object][]assignment{}
+	record := func(lhs ast.Expr, value ast.Expr, pos token.Pos) {
+		if id, ok := ast.Unparen(lhs).(*ast.Ident); ok {
+			if obj := pass.TypesInfo.ObjectOf(id); obj != nil {
+				writes[obj] = append(writes[obj], assignment{pos, value})
+			}
+		}
+	}
+	inspect.Preorder([]ast.Node{(*ast.AssignStmt)(nil), (*ast.IncDecStmt)(nil), (*ast.ValueSpec)(nil)}, func(n ast.Node) {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			for i, lhs := range n.Lhs {
+				var value ast.Expr
+				if (n.Tok == token.ASSIGN || n.Tok == token.DEFINE) && len(n.Lhs) == len(n.Rhs) {
+					value = n.Rhs[i]
+				}
+				record(lhs, value, n.Pos())
+			}
+		case *ast.IncDecStmt:
+			record(n.X, nil, n.Pos())
+		case *ast.ValueSpec:
+			for i, name := range n.Names {
+				var value ast.Expr
+				if len(n.Values) == len(n.Names) {
+					value = n.Values[i]
+				}
+				record(name, value, n.Pos())
+			}
+		}
+	})
+
+	inspect.WithStack([]ast.Node{(*ast.DeferStmt)(nil)}, func(n ast.Node, push bool, stack []ast.Node) bool {
+		if !push {
+			return true
+		}
+		d := n.(*ast.DeferStmt)
+		body, loop := enclosing(stack)
+		if body == nil {
+			return true
+		}
+
+		if loop != nil {
+			pass.Report(analysis.Diagnostic{
+				Pos: d.Pos(),
+				End: d.End(),
+				Message: fmt.Sprintf("defer inside the loop at line %d runs when the function returns, not at the end of each iteration; every iteration adds a call with its own argument values",
+					pass.Fset.Position(loop.Pos()).Line),
+			})
+		}
+
+		for _, v := range evaluatedVars(pass, d.Call) {
+			var later *assignment
+			var before *assignment
+			for i, w := range writes[v] {
+				if w.pos < body.Pos() || w.pos > body.End() {
+					continue
+				}
+				if w.pos < d.Pos() {
+					before = &writes[v][i]
+				} else if later == nil && w.pos > d.End() {
+					later = &writes[v][i]
+				}
+			}
+			if later == nil {
+				continue
+			}
+			value := "its value at line " + fmt.Sprint(pass.Fset.Position(d.Pos()).Line)
+			if before != nil && before.value != nil {
+				if tv, ok := pass.TypesInfo.Types[before.value]; ok && tv.Value != nil {
+					value = v.Name() + " = " + edit.Render(pass.Fset, before.value)
+				}
+			}
+			pass.Report(analysis.Diagnostic{
+				Pos: d.Pos(),
+				End: d.End(),
+				Message: fmt.Sprintf("deferred call evaluates %s right away and uses %s; the assignment at line %d doesn't affect it (defer a function literal to use the final value)",
+					v.Name(), value, pass.Fset.Position(later.pos).Line),
+			})
+		}
+		return true
+	})
+	return nil, nil
+}
+
+// enclosing returns the body of the innermost function on the stack and the
+// innermost loop between that function and the top of the stack, if any.
+func enclosing(stack []ast.Node) (*ast.BlockStmt, ast.Node) {
+	var loop ast.Node
+	for i := len(stack) - 1; i >= 0; i-- {
+		switch n := stack[i].(type) {
+		case *ast.ForStmt, *ast.RangeStmt:
+			if loop == nil {
+				loop = n
+			}
+		case *ast.FuncLit:
+			return n.Body, loop
+		case *ast.FuncDecl:
+			return n.Body, loop
+		}
+	}
+	return nil, nil
+}
+
+// evaluatedVars returns the local variables whose values the defer statement
+// copies: those in the arguments and the receiver, but not inside function
+// literals, which read them when they run.
+func evaluatedVars(pass *analysis.Pass, call *ast.CallExpr) []*types.Var {
+	var vars []*types.Var
+	seen := map[*types.Var]bool{}
+	visit := func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.UnaryExpr:
+			if n.Op == token.AND {
+				return false // &x is a pointer, the call sees later writes through it
+			}
+		case *ast.Ident:
+			v, ok := pass.TypesInfo.Uses[n].(*types.Var)
+			if ok && !v.IsField() && v.Parent() != v.Pkg().Scope() && !seen[v] {
+				seen[v] = true
+				vars = append(vars, v)
+			}
+		}
+		return true
+	}
+	for _, arg := range call.Args {
+		ast.Inspect(arg, visit)
+	}
+	if sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr); ok {
+		if s, ok := pass.TypesInfo.Selections[sel]; ok && s.Kind() == types.MethodVal {
+			// a value receiver is copied too; a pointer receiver takes &x, which sees later writes
+			recv := s.Obj().Type().(*types.Signature).Recv().Type()
+			if _, isPtr := recv.Underlying().(*types.Pointer); !isPtr {
+				ast.Inspect(sel.X, visit)
+			}
+		}
+	}
+	return vars
+}
@@ -0,0 +1,13 @@
+package deferargs_test
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+
+	"github.com/raproid/go-training/analyzers/deferargs"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), deferargs.Analyzer, "a")
+}
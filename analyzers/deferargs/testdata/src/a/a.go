@@ -0,0 +1,58 @@
+package a
+
+import (
+	"fmt"
+	"os"
+)
+
+func lesson() {
+	ac := "start"
+	defer fmt.Println(ac) // want `deferred call evaluates ac right away and uses ac = "start"; the assignment at line 11 doesn't affect it \(defer a function literal to use the final value\)`
+	ac = "end"
+}
+
+func computed(n int) {
+	total := n * 2
+	defer fmt.Println("total:", total) // want `deferred call evaluates total right away and uses its value at line 16; the assignment at line 17 doesn't affect it`
+	total++
+}
+
+func closures() {
+	ac := "start"
+	defer func() { fmt.Println(ac) }()
+	defer fmt.Println(&ac)
+	ac = "end"
+}
+
+func unchanged() {
+	ac := "start"
+	defer fmt.Println(ac)
+}
+
+type counter struct{ n int }
+
+func (c counter) Print()  { fmt.Println(c.n) }
+func (c *counter) Reset() { c.n = 0 }
+
+func receivers() {
+	var c counter
+	defer c.Print() // want `deferred call evaluates c right away and uses its value at line 39; the assignment at line 41 doesn't affect it`
+	defer c.Reset()
+	c = counter{n: 1}
+}
+
+func loop(names []string) error {
+	for _, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close() // want `defer inside the loop at line 45 runs when the function returns, not at the end of each iteration`
+	}
+	for range names {
+		func() {
+			defer fmt.Println("per iteration")
+		}()
+	}
+	return nil
+}
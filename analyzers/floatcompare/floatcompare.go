@@ -0,0 +1,139 @@
+// Package floatcompare defines an Analyzer that reports == and != between
+// floating-point operands.
+//
+// A float is an approximation of a decimal value, so the float lesson's
+//
+//	myNumber == math.Pow(math.Sqrt(myNumber), 2)
+//
+// is true for 0.1 but false for 0.123. Comparisons with a constant zero and the
+// x != x NaN check are exact and not reported.
+package floatcompare
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+
+	"github.com/raproid/go-training/analyzers/internal/edit"
+)
+
+const Doc = `report == and != between floating-point values
+
+Floating-point values are approximations, so two computations that are equal
+in decimal arithmetic may differ in the last bits. Compare with a tolerance
+instead, e.g. math.Abs(a-b) < 1e-9. Comparisons with a constant zero and the
+x != x NaN check are not reported.`
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "floatcompare",
+	Doc:      Doc,
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+var epsilon = "1e-9"
+
+func init() {
+	Analyzer.Flags.StringVar(&epsilon, "epsilon", epsilon, "tolerance used in the suggested fix")
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	var file *ast.File
+	nodeFilter := []ast.Node{(*ast.File)(nil), (*ast.BinaryExpr)(nil)}
+	inspect.Preorder(nodeFilter, func(n ast.Node) {
+		if f, ok := n.(*ast.File); ok {
+			file = f
+			return
+		}
+		e := n.(*ast.BinaryExpr)
+		if e.Op != token.EQL && e.Op != token.NEQ {
+			return
+		}
+		if !isFloat(pass.TypesInfo.TypeOf(e.X)) && !isFloat(pass.TypesInfo.TypeOf(e.Y)) {
+			return
+		}
+		if isZero(pass, e.X) || isZero(pass, e.Y) {
+			return // comparing with zero is exact
+		}
+		if edit.Render(pass.Fset, e.X) == edit.Render(pass.Fset, e.Y) {
+			return // x != x is the NaN check
+		}
+		if isConst(pass, e.X) && isConst(pass, e.Y) {
+			return // evaluated exactly by the compiler
+		}
+
+		pass.Report(analysis.Diagnostic{
+			Pos:     e.Pos(),
+			End:     e.End(),
+			Message: fmt.Sprintf("floating-point values compared with %s; use a tolerance such as math.Abs(a-b) < %s", e.Op, epsilon),
+			SuggestedFixes: []analysis.SuggestedFix{{
+				Message:   "Compare with a tolerance",
+				TextEdits: fix(pass, file, e),
+			}},
+		})
+	})
+	return nil, nil
+}
+
+// fix rewrites a == b to math.Abs(a-b) < epsilon (and != to >=), importing math if needed.
+func fix(pass *analysis.Pass, file *ast.File, e *ast.BinaryExpr) []analysis.TextEdit {
+	math, edits := edit.Import(file, "math")
+	y := edit.Render(pass.Fset, e.Y)
+	if !isPrimary(e.Y) {
+		y = "(" + y + ")" // a-(b+c), and a-(-b) rather than a--b
+	}
+	diff := edit.Render(pass.Fset, e.X) + "-" + y
+	if !is64(pass.TypesInfo.TypeOf(e.X)) || !is64(pass.TypesInfo.TypeOf(e.Y)) {
+		diff = "float64(" + diff + ")"
+	}
+	op := "<"
+	if e.Op == token.NEQ {
+		op = ">="
+	}
+	return append(edits, analysis.TextEdit{
+		Pos:     e.Pos(),
+		End:     e.End(),
+		NewText: []byte(fmt.Sprintf("%s.Abs(%s) %s %s", math, diff, op, epsilon)),
+	})
+}
+
+func isFloat(t types.Type) bool {
+	b, ok := t.Underlying().(*types.Basic)
+	return ok && b.Info()&types.IsFloat != 0
+}
+
+// is64 reports whether t can be passed to math.Abs without a conversion; a
+// named type like type celsius float64 can't.
+func is64(t types.Type) bool {
+	b, ok := t.(*types.Basic)
+	return ok && (b.Kind() == types.Float64 || b.Info()&types.IsUntyped != 0)
+}
+
+// isPrimary reports whether e is a primary expression, which binds tighter
+// than any operator and needs no parentheses as an operand.
+func isPrimary(e ast.Expr) bool {
+	switch e.(type) {
+	case *ast.Ident, *ast.BasicLit, *ast.CompositeLit, *ast.ParenExpr,
+		*ast.SelectorExpr, *ast.IndexExpr, *ast.IndexListExpr, *ast.SliceExpr,
+		*ast.TypeAssertExpr, *ast.CallExpr:
+		return true
+	}
+	return false
+}
+
+func isConst(pass *analysis.Pass, e ast.Expr) bool {
+	return pass.TypesInfo.Types[e].Value != nil
+}
+
+func isZero(pass *analysis.Pass, e ast.Expr) bool {
+	v := pass.TypesInfo.Types[e].Value
+	return v != nil && constant.Sign(v) == 0
+}
@@ -0,0 +1,20 @@
+package floatcompare_test
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+
+	"github.com/raproid/go-training/analyzers/floatcompare"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.RunWithSuggestedFixes(t, analysistest.TestData(), floatcompare.Analyzer, "a")
+}
+
+func TestEpsilon(t *testing.T) {
+	flag := floatcompare.Analyzer.Flags.Lookup("epsilon")
+	defer flag.Value.Set(flag.DefValue)
+	flag.Value.Set("1e-6")
+	analysistest.Run(t, analysistest.TestData(), floatcompare.Analyzer, "epsilon")
+}
@@ -0,0 +1,20 @@
+package a
+
+import "math"
+
+type celsius float64
+
+func compare(myNumber, y float64, f float32, t celsius, i int) {
+	_ = myNumber == math.Pow(math.Sqrt(myNumber), 2) // want `floating-point values compared with ==; use a tolerance such as math.Abs\(a-b\) < 1e-9`
+	_ = myNumber != 0.1                              // want `floating-point values compared with !=`
+	_ = f == 0.5                                     // want `floating-point values compared with ==`
+	_ = t == celsius(36.6)                           // want `floating-point values compared with ==`
+	_ = myNumber == myNumber*(1+0.1)                 // want `floating-point values compared with ==`
+	_ = myNumber == -y                               // want `floating-point values compared with ==`
+
+	_ = myNumber == 0
+	_ = 0.0 != f
+	_ = myNumber != myNumber
+	_ = 0.1+0.2 == 0.3
+	_ = i == 3
+}
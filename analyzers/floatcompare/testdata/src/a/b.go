@@ -0,0 +1,5 @@
+package a
+
+func half(x float64) bool {
+	return x/2 == 0.25 // want `floating-point values compared with ==`
+}
@@ -0,0 +1,5 @@
+package epsilon
+
+func equal(a, b float64) bool {
+	return a == b // want `use a tolerance such as math.Abs\(a-b\) < 1e-6`
+}
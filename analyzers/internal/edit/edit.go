@@ -0,0 +1,68 @@
+// Package edit holds helpers the analyzers share for building suggested fixes.
+package edit
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"strconv"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// Render returns the source of n as gofmt prints it.
+func Render(fset *token.FileSet, n ast.Node) string {
+	var b bytes.Buffer
+	format.Node(&b, fset, n)
+	return b.String()
+}
+
+// ImportName returns the name under which file imports path, and whether it does.
+func ImportName(file *ast.File, path string) (string, bool) {
+	for _, spec := range file.Imports {
+		if p, _ := strconv.Unquote(spec.Path.Value); p != path {
+			continue
+		}
+		if spec.Name != nil {
+			return spec.Name.Name, true
+		}
+		return defaultName(path), true
+	}
+	return defaultName(path), false
+}
+
+func defaultName(path string) string {
+	for i := len(path) - 1; i >= 0; i-- {
+		if path[i] == '/' {
+			return path[i+1:]
+		}
+	}
+	return path
+}
+
+// AddImport returns an edit that imports path, into the first import block if
+// there is one. Identical edits from several fixes are merged when applied.
+func AddImport(file *ast.File, path string) analysis.TextEdit {
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			continue
+		}
+		if gen.Lparen.IsValid() {
+			return analysis.TextEdit{Pos: gen.Lparen + 1, End: gen.Lparen + 1, NewText: []byte("\n\t" + strconv.Quote(path))}
+		}
+		return analysis.TextEdit{Pos: gen.Pos(), End: gen.Pos(), NewText: []byte("import " + strconv.Quote(path) + "\n")}
+	}
+	return analysis.TextEdit{Pos: file.Name.End(), End: file.Name.End(), NewText: []byte("\n\nimport " + strconv.Quote(path))}
+}
+
+// Import returns the name to refer to path by in file, plus the edits that
+// add the import if file doesn't have it yet.
+func Import(file *ast.File, path string) (string, []analysis.TextEdit) {
+	name, ok := ImportName(file, path)
+	if ok {
+		return name, nil
+	}
+	return name, []analysis.TextEdit{AddImport(file, path)}
+}
@@ -0,0 +1,330 @@
+// Package maporder defines an Analyzer that reports loops over maps whose
+// results depend on the iteration order.
+//
+// The maps lesson prints statePopulations with
+//
+//	for k, v := range statePopulations {
+//		fmt.Println(k, v)
+//	}
+//
+// and the states come out in a different order on every run, since Go
+// randomizes map iteration. That breaks golden files and makes reports hard to
+// compare. The analyzer reports loops over maps that write output, build a
+// string or append to a slice the function returns, and suggests ranging over
+// the sorted keys instead.
+package maporder
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"slices"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+
+	"github.com/raproid/go-training/analyzers/internal/edit"
+)
+
+const Doc = `report loops over maps whose output depends on the iteration order
+
+Map iteration order is unspecified and changes from run to run. A range loop
+over a map that prints, writes to an io.Writer, builds a string or appends to
+a slice that the function returns unsorted produces different results every
+time. Range over slices.Sorted(maps.Keys(m)) instead. Loops that use neither
+the key nor the value do the same thing in every order and aren't reported.`
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "maporder",
+	Doc:      Doc,
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+// printFuncs are the functions that write their arguments to an output.
+var printFuncs = map[string]bool{
+	"fmt.Print": true, "fmt.Println": true, "fmt.Printf": true,
+	"fmt.Fprint": true, "fmt.Fprintln": true, "fmt.Fprintf": true,
+	"log.Print": true, "log.Println": true, "log.Printf": true,
+	"io.WriteString": true,
+}
+
+// builders are the types whose Write methods build a string in memory.
+var builders = map[string]bool{
+	"strings.Builder": true,
+	"bytes.Buffer":    true,
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	var file *ast.File
+	inspect.WithStack([]ast.Node{(*ast.File)(nil), (*ast.RangeStmt)(nil)}, func(n ast.Node, push bool, stack []ast.Node) bool {
+		if !push {
+			return true
+		}
+		if f, ok := n.(*ast.File); ok {
+			file = f
+			return true
+		}
+		loop := n.(*ast.RangeStmt)
+		m, ok := pass.TypesInfo.TypeOf(loop.X).Underlying().(*types.Map)
+		if !ok || !usesKeyOrValue(pass, loop) {
+			return true
+		}
+		effect := orderDependent(pass, loop, enclosingBody(stack))
+		if effect == "" {
+			return true
+		}
+		pass.Report(analysis.Diagnostic{
+			Pos: loop.For,
+			End: loop.Body.Lbrace,
+			Message: fmt.Sprintf("the loop over map %s %s, so the result changes between runs; range over the sorted keys instead",
+				edit.Render(pass.Fset, loop.X), effect),
+			SuggestedFixes: fix(pass, file, loop, m),
+		})
+		return true
+	})
+	return nil, nil
+}
+
+// usesKeyOrValue reports whether the loop body refers to the key or the
+// value. If it doesn't, every iteration does the same thing and the order
+// can't show.
+func usesKeyOrValue(pass *analysis.Pass, loop *ast.RangeStmt) bool {
+	var vars []types.Object
+	for _, e := range []ast.Expr{loop.Key, loop.Value} {
+		if e == nil {
+			continue
+		}
+		id, ok := ast.Unparen(e).(*ast.Ident)
+		if !ok {
+			return true // assigned to something like a field, which the body may read
+		}
+		if obj := pass.TypesInfo.ObjectOf(id); obj != nil {
+			vars = append(vars, obj)
+		}
+	}
+	found := false
+	ast.Inspect(loop.Body, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && slices.Contains(vars, pass.TypesInfo.Uses[id]) {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// orderDependent describes the first thing the loop body does whose result
+// depends on the order of the iterations, or returns "".
+func orderDependent(pass *analysis.Pass, loop *ast.RangeStmt, fn *ast.BlockStmt) string {
+	declaredOutside := func(e ast.Expr) (*types.Var, bool) {
+		id, ok := ast.Unparen(e).(*ast.Ident)
+		if !ok {
+			return nil, false
+		}
+		v, ok := pass.TypesInfo.ObjectOf(id).(*types.Var)
+		return v, ok && (v.Pos() < loop.Pos() || v.Pos() > loop.End())
+	}
+
+	var effect string
+	ast.Inspect(loop.Body, func(n ast.Node) bool {
+		if effect != "" {
+			return false
+		}
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false // it may never be called in the loop
+		case *ast.AssignStmt:
+			if n.Tok == token.ADD_ASSIGN && len(n.Lhs) == 1 {
+				if v, ok := declaredOutside(n.Lhs[0]); ok && isString(v.Type()) {
+					effect = "builds the string " + v.Name()
+				}
+			}
+			if n.Tok == token.ASSIGN && len(n.Lhs) == 1 && len(n.Rhs) == 1 {
+				call, ok := ast.Unparen(n.Rhs[0]).(*ast.CallExpr)
+				if !ok || !isBuiltin(pass, call, "append") {
+					break
+				}
+				if v, ok := declaredOutside(n.Lhs[0]); ok && fn != nil {
+					if line := returnedUnsorted(pass, v, loop, fn); line > 0 {
+						effect = fmt.Sprintf("appends to %s, which is returned at line %d", v.Name(), line)
+					}
+				}
+			}
+		case *ast.CallExpr:
+			if isBuiltin(pass, n, "print") || isBuiltin(pass, n, "println") {
+				effect = "writes output"
+				break
+			}
+			fn, ok := typeutil.Callee(pass.TypesInfo, n).(*types.Func)
+			if !ok || fn.Pkg() == nil {
+				break
+			}
+			name := fn.Pkg().Path() + "." + fn.Name()
+			if recv := fn.Signature().Recv(); recv != nil {
+				if builders[typeName(recv.Type())] {
+					if isWrite(fn.Name()) {
+						effect = "builds a string with " + edit.Render(pass.Fset, n.Fun)
+					}
+					break
+				}
+				if isWrite(fn.Name()) || fn.Name() == "Encode" {
+					effect = "writes output with " + edit.Render(pass.Fset, n.Fun)
+				}
+				break
+			}
+			if printFuncs[name] {
+				effect = "writes output with " + edit.Render(pass.Fset, n.Fun)
+				if len(n.Args) > 0 && (fn.Name() == "WriteString" || fn.Name()[0] == 'F') {
+					if builders[typeName(pass.TypesInfo.TypeOf(n.Args[0]))] {
+						effect = "builds a string with " + edit.Render(pass.Fset, n.Fun)
+					}
+				}
+			}
+		}
+		return true
+	})
+	return effect
+}
+
+// returnedUnsorted returns the line of the first return statement after the
+// loop that returns v, or 0 if there's none or v gets sorted before it.
+func returnedUnsorted(pass *analysis.Pass, v *types.Var, loop *ast.RangeStmt, fn *ast.BlockStmt) int {
+	mentions := func(n ast.Node) bool {
+		found := false
+		ast.Inspect(n, func(n ast.Node) bool {
+			if id, ok := n.(*ast.Ident); ok && pass.TypesInfo.Uses[id] == v {
+				found = true
+			}
+			return !found
+		})
+		return found
+	}
+
+	line := 0
+	sorted := false
+	ast.Inspect(fn, func(n ast.Node) bool {
+		if n == nil || line > 0 || sorted || n.End() <= loop.End() {
+			return false // done, or entirely before the end of the loop
+		}
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.CallExpr:
+			if f, ok := typeutil.Callee(pass.TypesInfo, n).(*types.Func); ok && f.Pkg() != nil &&
+				(f.Pkg().Path() == "sort" || f.Pkg().Path() == "slices" && len(f.Name()) >= 4 && f.Name()[:4] == "Sort") {
+				for _, arg := range n.Args {
+					if mentions(arg) {
+						sorted = true
+					}
+				}
+			}
+		case *ast.ReturnStmt:
+			for _, r := range n.Results {
+				if mentions(r) {
+					line = pass.Fset.Position(n.Pos()).Line
+				}
+			}
+		}
+		return true
+	})
+	return line
+}
+
+// fix rewrites the loop header to range over the sorted keys and looks up
+// the value at the top of the body. It's only offered for ordered key types
+// and a map expression that is safe to evaluate more than once.
+func fix(pass *analysis.Pass, file *ast.File, loop *ast.RangeStmt, m *types.Map) []analysis.SuggestedFix {
+	if b, ok := m.Key().Underlying().(*types.Basic); !ok || b.Info()&types.IsOrdered == 0 {
+		return nil
+	}
+	switch ast.Unparen(loop.X).(type) {
+	case *ast.Ident, *ast.SelectorExpr:
+	default:
+		return nil
+	}
+	if loop.Tok != token.DEFINE && loop.Key != nil {
+		return nil // assigning to existing variables; keep it simple
+	}
+	if !isNamed(loop.Key) && !isNamed(loop.Value) {
+		return nil // for range m: the new loop would declare a key it never uses
+	}
+
+	key := "k"
+	if isNamed(loop.Key) {
+		key = loop.Key.(*ast.Ident).Name
+	} else if scope := pass.TypesInfo.Scopes[loop]; scope != nil {
+		if _, obj := scope.LookupParent(key, loop.Body.Lbrace); obj != nil {
+			return nil
+		}
+	}
+
+	slices, edits := edit.Import(file, "slices")
+	maps, more := edit.Import(file, "maps")
+	edits = append(edits, more...)
+
+	x := edit.Render(pass.Fset, loop.X)
+	header := fmt.Sprintf("for _, %s := range %s.Sorted(%s.Keys(%s)) {", key, slices, maps, x)
+	edits = append(edits, analysis.TextEdit{Pos: loop.For, End: loop.Body.Lbrace + 1, NewText: []byte(header)})
+	if isNamed(loop.Value) {
+		lookup := fmt.Sprintf("\n%s := %s[%s]", loop.Value.(*ast.Ident).Name, x, key)
+		edits = append(edits, analysis.TextEdit{Pos: loop.Body.Lbrace + 1, End: loop.Body.Lbrace + 1, NewText: []byte(lookup)})
+	}
+	return []analysis.SuggestedFix{{Message: "Range over the sorted keys", TextEdits: edits}}
+}
+
+// enclosingBody returns the body of the innermost function on the stack.
+func enclosingBody(stack []ast.Node) *ast.BlockStmt {
+	for i := len(stack) - 1; i >= 0; i-- {
+		switch n := stack[i].(type) {
+		case *ast.FuncLit:
+			return n.Body
+		case *ast.FuncDecl:
+			return n.Body
+		}
+	}
+	return nil
+}
+
+// isNamed reports whether e is a loop variable other than _.
+func isNamed(e ast.Expr) bool {
+	id, ok := e.(*ast.Ident)
+	return ok && id.Name != "_"
+}
+
+func isBuiltin(pass *analysis.Pass, call *ast.CallExpr, name string) bool {
+	id, ok := ast.Unparen(call.Fun).(*ast.Ident)
+	if !ok {
+		return false
+	}
+	b, ok := pass.TypesInfo.Uses[id].(*types.Builtin)
+	return ok && b.Name() == name
+}
+
+func isString(t types.Type) bool {
+	b, ok := t.Underlying().(*types.Basic)
+	return ok && b.Info()&types.IsString != 0
+}
+
+func isWrite(name string) bool {
+	switch name {
+	case "Write", "WriteString", "WriteByte", "WriteRune":
+		return true
+	}
+	return false
+}
+
+// typeName returns pkg.Name for a (pointer to a) named type, or "".
+func typeName(t types.Type) string {
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	if n, ok := t.(*types.Named); ok && n.Obj().Pkg() != nil {
+		return n.Obj().Pkg().Path() + "." + n.Obj().Name()
+	}
+	return ""
+}
@@ -0,0 +1,13 @@
+package maporder_test
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+
+	"github.com/raproid/go-training/analyzers/maporder"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.RunWithSuggestedFixes(t, analysistest.TestData(), maporder.Analyzer, "a")
+}
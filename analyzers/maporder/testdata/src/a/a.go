@@ -0,0 +1,79 @@
+package a
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var statePopulations = map[string]int{
+	"CA": 39250017,
+	"TX": 27862596,
+	"FL": 20612439,
+}
+
+func printAll() {
+	for k, v := range statePopulations { // want `the loop over map statePopulations writes output with fmt.Println, so the result changes between runs; range over the sorted keys instead`
+		fmt.Println(k, v)
+	}
+	for k := range statePopulations { // want `writes output with fmt.Println`
+		fmt.Println(k)
+	}
+	for _, v := range statePopulations { // want `writes output with fmt.Println`
+		fmt.Println(v)
+	}
+}
+
+func build(m map[int]string) string {
+	var b strings.Builder
+	for id, name := range m { // want `the loop over map m builds a string with b.WriteString`
+		b.WriteString(fmt.Sprint(id, name))
+	}
+	s := ""
+	for _, name := range m { // want `the loop over map m builds the string s`
+		s += name
+	}
+	return b.String() + s
+}
+
+func keys(m map[string]int) []string {
+	var out []string
+	for k := range m { // want `the loop over map m appends to out, which is returned at line 44`
+		out = append(out, k)
+	}
+	return out
+}
+
+func sortedKeys(m map[string]int) []string {
+	var out []string
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func noKeyOrValue(m map[string]int) {
+	for range m {
+		fmt.Println("one more state")
+	}
+	for _, _ = range m {
+		fmt.Println("one more state")
+	}
+}
+
+func sum(m map[string]int) int {
+	total := 0
+	for _, v := range m {
+		total += v
+	}
+	return total
+}
+
+type point struct{ x, y int }
+
+func unordered(m map[point]string) {
+	for p, name := range m { // want `writes output with fmt.Println`
+		fmt.Println(p, name)
+	}
+}
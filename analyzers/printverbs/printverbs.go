@@ -0,0 +1,314 @@
+// Package printverbs defines an Analyzer that reports formatting directives
+// passed to Print-style functions and Printf directives that don't match
+// their arguments.
+//
+// The defer lesson prints the robots.txt file with
+//
+//	fmt.Println("%s", string(robots))
+//
+// which prints a literal "%s" followed by the file; fmt.Printf("%s\n", ...)
+// was meant. The suggested fix rewrites such calls to the f-variant.
+package printverbs
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+const Doc = `report formatting directives in Print calls and mismatched Printf verbs
+
+Print, Println and their log, Fprint and Sprint relatives don't interpret
+format strings, so fmt.Println("%s", x) prints "%s" literally; the suggested
+fix switches to the f-variant. Printf-style calls are checked for verbs that
+can't format their argument (%d with a string, %s with a bool, ...) and for
+too few or too many arguments.`
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "printverbs",
+	Doc:      Doc,
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+// printFuncs maps the Print-style functions to their f-variant; the value
+// also tells how many leading arguments (like an io.Writer) come before the
+// printed values.
+var printFuncs = map[string]struct {
+	f    string
+	skip int
+}{
+	"fmt.Print":    {"Printf", 0},
+	"fmt.Println":  {"Printf", 0},
+	"fmt.Sprint":   {"Sprintf", 0},
+	"fmt.Sprintln": {"Sprintf", 0},
+	"fmt.Fprint":   {"Fprintf", 1},
+	"fmt.Fprintln": {"Fprintf", 1},
+	"log.Print":    {"Printf", 0},
+	"log.Println":  {"Printf", 0},
+	"log.Fatal":    {"Fatalf", 0},
+	"log.Fatalln":  {"Fatalf", 0},
+	"log.Panic":    {"Panicf", 0},
+	"log.Panicln":  {"Panicf", 0},
+}
+
+// printfFuncs maps the Printf-style functions to the index of their format argument.
+var printfFuncs = map[string]int{
+	"fmt.Printf":  0,
+	"fmt.Sprintf": 0,
+	"fmt.Errorf":  0,
+	"fmt.Fprintf": 1,
+	"fmt.Appendf": 1,
+	"log.Printf":  0,
+	"log.Fatalf":  0,
+	"log.Panicf":  0,
+}
+
+// directive matches a formatting directive such as %s, %-8.3f or %[2]*d, but not %%.
+var directive = regexp.MustCompile(`%(\[\d+\])?[-+# 0]*(\[\d+\])?(\d+|\*)?(\.(\[\d+\])?(\d+|\*)?)?(\[\d+\])?[a-zA-Z]`)
+
+// printDirective is directive without the space flag, for the Print check:
+// "50% off" and "100% done" are text, not the directives % o and % d.
+var printDirective = regexp.MustCompile(`%(\[\d+\])?[-+#0]*(\[\d+\])?(\d+|\*)?(\.(\[\d+\])?(\d+|\*)?)?(\[\d+\])?[a-zA-Z]`)
+
+func run(pass *analysis.Pass) (any, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	inspect.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+		call := n.(*ast.CallExpr)
+		name := funcName(pass, call)
+		if p, ok := printFuncs[name]; ok {
+			checkPrint(pass, call, name, p.f, p.skip)
+		}
+		if i, ok := printfFuncs[name]; ok {
+			checkPrintf(pass, call, name, i)
+		}
+	})
+	return nil, nil
+}
+
+// funcName returns pkg.Func for calls of package-level functions and methods
+// of log.Logger (as log.Func), or "".
+func funcName(pass *analysis.Pass, call *ast.CallExpr) string {
+	fn, ok := typeutil.Callee(pass.TypesInfo, call).(*types.Func)
+	if !ok || fn.Pkg() == nil {
+		return ""
+	}
+	return fn.Pkg().Path() + "." + fn.Name()
+}
+
+func checkPrint(pass *analysis.Pass, call *ast.CallExpr, name, fname string, skip int) {
+	if len(call.Args) <= skip || call.Ellipsis.IsValid() {
+		return
+	}
+	first := call.Args[skip]
+	format, ok := stringConst(pass, first)
+	if !ok {
+		return
+	}
+	d := printDirective.FindString(strings.ReplaceAll(format, "%%", ""))
+	if d == "" {
+		return
+	}
+
+	diag := analysis.Diagnostic{
+		Pos:     call.Pos(),
+		End:     call.End(),
+		Message: fmt.Sprintf("%s call has formatting directive %s; it's printed as is, use %s", name, d, fname),
+	}
+	// the fix needs to edit the literal itself, so it's only offered for "..." literals
+	if lit, ok := ast.Unparen(first).(*ast.BasicLit); ok && lit.Kind == token.STRING && strings.HasPrefix(lit.Value, `"`) {
+		newline := strings.HasSuffix(name, "ln")
+		fun := call.Fun
+		if sel, ok := ast.Unparen(fun).(*ast.SelectorExpr); ok {
+			fun = sel.Sel
+		}
+		literal := lit.Value
+		if newline {
+			literal = literal[:len(literal)-1] + `\n"`
+		}
+		diag.SuggestedFixes = []analysis.SuggestedFix{{
+			Message: "Use " + fname,
+			TextEdits: []analysis.TextEdit{
+				{Pos: fun.Pos(), End: fun.End(), NewText: []byte(fname)},
+				{Pos: lit.Pos(), End: lit.End(), NewText: []byte(literal)},
+			},
+		}}
+	}
+	pass.Report(diag)
+}
+
+func checkPrintf(pass *analysis.Pass, call *ast.CallExpr, name string, formatIndex int) {
+	if len(call.Args) <= formatIndex || call.Ellipsis.IsValid() {
+		return // a forwarded args... can't be checked
+	}
+	format, ok := stringConst(pass, call.Args[formatIndex])
+	if !ok {
+		return
+	}
+	args := call.Args[formatIndex+1:]
+	argNum := 0
+	maxUsed := 0
+	use := func(verb string, check func(types.Type) bool, want string) bool {
+		if argNum >= len(args) {
+			pass.Reportf(call.Pos(), "%s format %s reads argument #%d, but the call has %d", name, verb, argNum+1, len(args))
+			return false
+		}
+		arg := args[argNum]
+		argNum++
+		maxUsed = max(maxUsed, argNum)
+		if t := pass.TypesInfo.TypeOf(arg); t != nil && !check(t) {
+			pass.ReportRangef(arg, "%s format %s has argument of wrong type %s, want %s", name, verb, t, want)
+		}
+		return true
+	}
+
+	for _, loc := range directive.FindAllStringIndex(strings.ReplaceAll(format, "%%", "  "), -1) {
+		verb := format[loc[0]:loc[1]]
+		spec := verb[1 : len(verb)-1]
+		// walk the flags, width and precision; [n] moves to argument n, * consumes an int
+		for i := 0; i < len(spec); i++ {
+			switch spec[i] {
+			case '[':
+				end := strings.IndexByte(spec[i:], ']')
+				n, err := strconv.Atoi(spec[i+1 : i+end])
+				if err != nil || n < 1 {
+					pass.Reportf(call.Pos(), "%s format %s has bad argument index %s", name, verb, spec[i:i+end+1])
+					return
+				}
+				argNum = n - 1
+				i += end
+			case '*':
+				if !use(verb, isInteger, "int for *") {
+					return
+				}
+			}
+		}
+		check, want := verbCheck(verb[len(verb)-1])
+		if check == nil {
+			pass.Reportf(call.Pos(), "%s format %s has unknown verb %c", name, verb, verb[len(verb)-1])
+			return
+		}
+		if !use(verb, check, want) {
+			return
+		}
+	}
+	if !strings.Contains(format, "[") && maxUsed < len(args) {
+		pass.ReportRangef(args[maxUsed], "%s call needs %d arguments but has %d", name, maxUsed, len(args))
+	}
+}
+
+// verbCheck returns the test an argument of verb must pass and a description of it.
+func verbCheck(verb byte) (func(types.Type) bool, string) {
+	switch verb {
+	case 'v', 'T':
+		return func(types.Type) bool { return true }, "any value"
+	case 't':
+		return elems(isBool), "bool"
+	case 'd', 'c', 'U', 'o', 'O':
+		return elems(isInteger), "integer"
+	case 'b':
+		return elems(func(t types.Type) bool { return isInteger(t) || isFloat(t) }), "integer or float"
+	case 'e', 'E', 'f', 'F', 'g', 'G':
+		return elems(isFloat), "float or complex"
+	case 's':
+		return elems(isString), "string, []byte, error or fmt.Stringer"
+	case 'q':
+		return elems(func(t types.Type) bool { return isString(t) || isInteger(t) }), "string or rune"
+	case 'x', 'X':
+		return elems(func(t types.Type) bool { return isString(t) || isInteger(t) || isFloat(t) }), "string, integer or float"
+	case 'w':
+		return func(t types.Type) bool {
+			_, isIface := t.Underlying().(*types.Interface)
+			return isIface || hasMethod(t, "Error")
+		}, "error"
+	case 'p':
+		return func(t types.Type) bool {
+			switch t.Underlying().(type) {
+			case *types.Pointer, *types.Slice, *types.Map, *types.Chan, *types.Signature:
+				return true
+			}
+			return isFormatter(t)
+		}, "pointer"
+	}
+	return nil, ""
+}
+
+// elems wraps a check so that it also accepts values fmt formats element by
+// element (slices, arrays, maps, pointers to them) and values that format
+// themselves or whose type we can't know.
+func elems(check func(types.Type) bool) func(types.Type) bool {
+	var f func(t types.Type, depth int) bool
+	f = func(t types.Type, depth int) bool {
+		if depth > 3 || check(t) || isFormatter(t) {
+			return true
+		}
+		switch u := t.Underlying().(type) {
+		case *types.Interface, *types.TypeParam:
+			return true
+		case *types.Struct:
+			return true // every field is formatted with the verb; too deep to be worth checking
+		case *types.Slice:
+			return f(u.Elem(), depth+1)
+		case *types.Array:
+			return f(u.Elem(), depth+1)
+		case *types.Map:
+			return f(u.Key(), depth+1) && f(u.Elem(), depth+1)
+		case *types.Pointer:
+			return depth == 0 && f(u.Elem(), depth+1)
+		}
+		return false
+	}
+	return func(t types.Type) bool { return f(t, 0) }
+}
+
+func basicInfo(t types.Type) types.BasicInfo {
+	if b, ok := t.Underlying().(*types.Basic); ok {
+		return b.Info()
+	}
+	return 0
+}
+
+func isBool(t types.Type) bool    { return basicInfo(t)&types.IsBoolean != 0 }
+func isInteger(t types.Type) bool { return basicInfo(t)&types.IsInteger != 0 }
+func isFloat(t types.Type) bool   { return basicInfo(t)&(types.IsFloat|types.IsComplex) != 0 }
+
+func isString(t types.Type) bool {
+	if basicInfo(t)&types.IsString != 0 {
+		return true
+	}
+	if s, ok := t.Underlying().(*types.Slice); ok && basicInfo(s.Elem())&types.IsInteger != 0 {
+		b, _ := s.Elem().Underlying().(*types.Basic)
+		return b.Kind() == types.Byte
+	}
+	return hasMethod(t, "Error") || hasMethod(t, "String")
+}
+
+// isFormatter reports whether t implements fmt.Formatter, which handles every verb itself.
+func isFormatter(t types.Type) bool {
+	return hasMethod(t, "Format")
+}
+
+func hasMethod(t types.Type, name string) bool {
+	obj, _, _ := types.LookupFieldOrMethod(t, true, nil, name)
+	_, ok := obj.(*types.Func)
+	return ok
+}
+
+func stringConst(pass *analysis.Pass, e ast.Expr) (string, bool) {
+	v := pass.TypesInfo.Types[e].Value
+	if v == nil || v.Kind() != constant.String {
+		return "", false
+	}
+	return constant.StringVal(v), true
+}
@@ -0,0 +1,13 @@
+package printverbs_test
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+
+	"github.com/raproid/go-training/analyzers/printverbs"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.RunWithSuggestedFixes(t, analysistest.TestData(), printverbs.Analyzer, "a")
+}
@@ -0,0 +1,56 @@
+package a
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+)
+
+type celsius float64
+
+func (c celsius) String() string { return fmt.Sprintf("%.1f°C", float64(c)) }
+
+func prints(robots []byte, name string) {
+	fmt.Println("%s", string(robots)) // want `fmt.Println call has formatting directive %s; it's printed as is, use Printf`
+	fmt.Print("%d items\n", 3)        // want `fmt.Print call has formatting directive %d`
+	log.Println("user %q", name)      // want `log.Println call has formatting directive %q; it's printed as is, use Printf`
+	_ = fmt.Sprintln("%-8.3f", 1.5)   // want `fmt.Sprintln call has formatting directive %-8.3f`
+	fmt.Fprintln(os.Stderr, "%v", 1)  // want `fmt.Fprintln call has formatting directive %v; it's printed as is, use Fprintf`
+	fmt.Println(`raw %s`, name)       // want `fmt.Println call has formatting directive %s`
+
+	fmt.Println("50% off today")
+	fmt.Println("100% done")
+	fmt.Println("100%% sure", name)
+	fmt.Println("no directives", name)
+	fmt.Println(name, "%s")
+}
+
+func printfs(name string, n int, err error, t celsius, ok bool) {
+	fmt.Printf("%s is %d\n", name, n)
+	fmt.Printf("%d\n", name) // want `fmt.Printf format %d has argument of wrong type string, want integer`
+	fmt.Printf("%s\n", ok)   // want `fmt.Printf format %s has argument of wrong type bool, want string, \[\]byte, error or fmt.Stringer`
+	fmt.Printf("%s %v %.2f\n", t, t, t)
+	fmt.Printf("%s and %s\n", name) // want `fmt.Printf format %s reads argument #2, but the call has 1`
+	fmt.Printf("%s\n", name, n)     // want `fmt.Printf call needs 1 arguments but has 2`
+	fmt.Printf("%z\n", n)           // want `fmt.Printf format %z has unknown verb z`
+	fmt.Printf("%*d\n", n, n)
+	fmt.Printf("%*d\n", name, n) // want `fmt.Printf format %\*d has argument of wrong type string, want int for \*`
+	fmt.Printf("%[2]d %[1]s\n", name, n)
+	fmt.Printf("%[0]d\n", n) // want `fmt.Printf format %\[0\]d has bad argument index \[0\]`
+	fmt.Printf("%[3]d\n", n) // want `fmt.Printf format %\[3\]d reads argument #3, but the call has 1`
+	fmt.Printf("%d%%\n", n)
+	fmt.Printf("% d\n", n)
+	fmt.Printf("%v %T\n", []string{name}, err)
+	fmt.Printf("%d\n", []int{n})
+	fmt.Printf("%s\n", []byte(name))
+	_ = fmt.Errorf("open %s: %w", name, err)
+	_ = fmt.Errorf("open: %w", name) // want `fmt.Errorf format %w has argument of wrong type string, want error`
+	fmt.Printf("%p\n", &n)
+	fmt.Printf("%p\n", n) // want `fmt.Printf format %p has argument of wrong type int, want pointer`
+	log.Printf("%t\n", ok)
+
+	args := []any{name}
+	fmt.Printf("%d\n", args...)
+	_ = errors.New("%d")
+}
@@ -0,0 +1,159 @@
+// Package shadow defines an Analyzer that reports variables and constants
+// declared in an inner scope under a name that is already in use outside it.
+//
+// In test.go, main() starts with
+//
+//	var i float32 = 42.5
+//
+// and later both `switch i := 3 + 5; i` and `for i := 0; ...` declare a new
+// i, so inside them the float is out of reach. The constants lesson notes the
+// same for constants declared inside a function that reuse a package-level
+// name. Both declaration sites are reported.
+package shadow
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+const Doc = `report variables and constants that shadow an outer declaration
+
+A declaration in an inner scope (an if, switch or for statement, a block, a
+function literal) that reuses a name from an outer scope hides the outer
+variable or constant until the scope ends. The x := x idiom is not reported.
+
+Use -ignore to skip names such as err (the default) and -maxlines to skip
+shadowing in scopes of at most that many lines.`
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "shadow",
+	Doc:      Doc,
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+var (
+	ignore   = "err"
+	maxLines = 0
+)
+
+func init() {
+	Analyzer.Flags.StringVar(&ignore, "ignore", ignore, "comma-separated names whose shadowing isn't reported")
+	Analyzer.Flags.IntVar(&maxLines, "maxlines", maxLines, "don't report shadowing in scopes of at most this many lines; 0 reports all")
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	ignored := map[string]bool{}
+	for _, name := range strings.Split(ignore, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			ignored[name] = true
+		}
+	}
+
+	// receivers, parameters and results are part of the function's signature, so they aren't reported
+	params := map[*ast.Ident]bool{}
+	// x := x copies the outer value on purpose, e.g. to capture a loop variable
+	copies := map[*ast.Ident]bool{}
+	addParams := func(lists ...*ast.FieldList) {
+		for _, list := range lists {
+			if list == nil {
+				continue
+			}
+			for _, f := range list.List {
+				for _, name := range f.Names {
+					params[name] = true
+				}
+			}
+		}
+	}
+	inspect.Preorder([]ast.Node{(*ast.FuncDecl)(nil), (*ast.FuncType)(nil), (*ast.AssignStmt)(nil)}, func(n ast.Node) {
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			addParams(n.Recv) // the receiver isn't part of the FuncType
+		case *ast.FuncType:
+			addParams(n.TypeParams, n.Params, n.Results)
+		case *ast.AssignStmt:
+			if n.Tok != token.DEFINE || len(n.Lhs) != len(n.Rhs) {
+				return
+			}
+			for i, lhs := range n.Lhs {
+				l, ok1 := lhs.(*ast.Ident)
+				r, ok2 := n.Rhs[i].(*ast.Ident)
+				if ok1 && ok2 && l.Name == r.Name {
+					copies[l] = true
+				}
+			}
+		}
+	})
+
+	// Defs is a map, so sort its identifiers to report in source order on every run
+	ids := make([]*ast.Ident, 0, len(pass.TypesInfo.Defs))
+	for id := range pass.TypesInfo.Defs {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].Pos() < ids[j].Pos() })
+
+	for _, id := range ids {
+		obj := pass.TypesInfo.Defs[id]
+		if obj == nil || id.Name == "_" || ignored[id.Name] || params[id] || copies[id] || !isValue(obj) {
+			continue
+		}
+		scope := obj.Parent()
+		if scope == nil || scope == pass.Pkg.Scope() || scope.Parent() == nil {
+			continue
+		}
+		_, outer := scope.Parent().LookupParent(id.Name, id.Pos())
+		if outer == nil || !isValue(outer) || outer.Parent() == types.Universe {
+			continue
+		}
+		if maxLines > 0 {
+			lines := pass.Fset.Position(scope.End()).Line - pass.Fset.Position(scope.Pos()).Line + 1
+			if lines <= maxLines {
+				continue
+			}
+		}
+
+		outerPos := pass.Fset.Position(outer.Pos())
+		pass.Report(analysis.Diagnostic{
+			Pos: id.Pos(),
+			End: id.End(),
+			Message: fmt.Sprintf("declaration of %s %q shadows %s declared at %s:%d",
+				kind(obj), id.Name, kind(outer), filepath.Base(outerPos.Filename), outerPos.Line),
+			Related: []analysis.RelatedInformation{{
+				Pos:     outer.Pos(),
+				End:     outer.Pos() + token.Pos(len(outer.Name())),
+				Message: fmt.Sprintf("shadowed %s %q", kind(outer), outer.Name()),
+			}},
+		})
+	}
+	return nil, nil
+}
+
+// isValue reports whether obj is a variable (but not a struct field) or a constant.
+func isValue(obj types.Object) bool {
+	switch obj := obj.(type) {
+	case *types.Var:
+		return !obj.IsField()
+	case *types.Const:
+		return true
+	}
+	return false
+}
+
+func kind(obj types.Object) string {
+	if _, ok := obj.(*types.Const); ok {
+		return "constant"
+	}
+	return "variable"
+}
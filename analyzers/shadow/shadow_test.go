@@ -0,0 +1,26 @@
+package shadow_test
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+
+	"github.com/raproid/go-training/analyzers/shadow"
+)
+
+func TestAnalyzer(t *testing.T) {
+	for _, r := range analysistest.Run(t, analysistest.TestData(), shadow.Analyzer, "a") {
+		for i := 1; i < len(r.Diagnostics); i++ {
+			if r.Diagnostics[i].Pos < r.Diagnostics[i-1].Pos {
+				t.Errorf("diagnostics out of source order: %q before %q", r.Diagnostics[i-1].Message, r.Diagnostics[i].Message)
+			}
+		}
+	}
+}
+
+func TestMaxLines(t *testing.T) {
+	flag := shadow.Analyzer.Flags.Lookup("maxlines")
+	defer flag.Value.Set(flag.DefValue)
+	flag.Value.Set("4")
+	analysistest.Run(t, analysistest.TestData(), shadow.Analyzer, "maxlines")
+}
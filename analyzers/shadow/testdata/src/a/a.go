@@ -0,0 +1,65 @@
+package a
+
+import "fmt"
+
+const myConst = 53
+
+func main() {
+	var i float32 = 42.5
+	fmt.Println(i)
+
+	switch i := 3 + 5; i { // want `declaration of variable "i" shadows variable declared at a.go:8`
+	case 8:
+		fmt.Println("eight")
+	}
+	for i := 0; i < 5; i++ { // want `declaration of variable "i" shadows variable declared at a.go:8`
+		fmt.Println(i)
+	}
+	if ok := i > 0; ok {
+		i := "a string" // want `declaration of variable "i" shadows variable declared at a.go:8`
+		fmt.Println(i)
+	}
+
+	const myConst int = 54 // want `declaration of constant "myConst" shadows constant declared at a.go:5`
+	fmt.Println(myConst)
+
+	go func() {
+		var i, j = 1, 2 // want `declaration of variable "i" shadows variable declared at a.go:8`
+		fmt.Println(i, j)
+	}()
+}
+
+func idioms(xs []int, n int) error {
+	var err error
+	for _, x := range xs {
+		x := x
+		go fmt.Println(x)
+		if _, err := fmt.Println(x); err != nil {
+			return err
+		}
+	}
+	{
+		n := n * 2 // want `declaration of variable "n" shadows variable declared at a.go:32`
+		fmt.Println(n)
+	}
+	type point struct{ xs []int }
+	len := len(xs)
+	fmt.Println(point{xs}, len)
+	return err
+}
+
+var total int
+
+type tally struct{ n int }
+
+// a receiver or a parameter named like a package variable isn't reported
+func (total *tally) add(n int) {
+	total.n += n
+}
+
+func sum(total int, xs ...int) int {
+	for _, x := range xs {
+		total += x
+	}
+	return total
+}
@@ -0,0 +1,16 @@
+package maxlines
+
+import "fmt"
+
+func short(x int) {
+	if x > 0 {
+		x := 1
+		fmt.Println(x)
+	}
+	for i := 0; i < 3; i++ {
+		x := i // want `declaration of variable "x" shadows variable declared at maxlines.go:5`
+		fmt.Println(x)
+		fmt.Println(i)
+		fmt.Println(i * 2)
+	}
+}
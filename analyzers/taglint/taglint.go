@@ -0,0 +1,151 @@
+// Package taglint defines an Analyzer that checks the syntax of struct tags.
+//
+// The struct tags lesson declares
+//
+//	Name string `required max: "100"`
+//
+// which isn't a list of key:"value" pairs, so reflect.StructTag.Get can't read
+// any key from it. The analyzer reports such tags at the offending character,
+// as well as keys that appear twice and keys that aren't in the allowlist.
+package taglint
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+const Doc = `check that struct tags are well-formed key:"value" lists
+
+reflect.StructTag.Get only understands tags made of space-separated
+key:"value" pairs. Malformed tags are reported at the offending character,
+as are keys that appear twice and keys missing from the -keys allowlist.`
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "taglint",
+	Doc:      Doc,
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+var keys = "json,xml,yaml,toml,csv,validate,db,bson,mapstructure,form,query,env,default,protobuf,msgpack"
+
+func init() {
+	Analyzer.Flags.StringVar(&keys, "keys", keys, `comma-separated tag keys that are allowed; "" allows any key`)
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	allowed := map[string]bool{}
+	for _, k := range strings.Split(keys, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			allowed[k] = true
+		}
+	}
+
+	inspect.Preorder([]ast.Node{(*ast.StructType)(nil)}, func(n ast.Node) {
+		for _, field := range n.(*ast.StructType).Fields.List {
+			if field.Tag == nil {
+				continue
+			}
+			tag, err := strconv.Unquote(field.Tag.Value)
+			if err != nil {
+				continue // the compiler reports it
+			}
+			checkTag(pass, field.Tag, tag, allowed)
+		}
+	})
+	return nil, nil
+}
+
+// checkTag parses tag the way reflect.StructTag.Lookup does and reports the
+// first syntax error, duplicate keys and keys that aren't allowed.
+func checkTag(pass *analysis.Pass, lit *ast.BasicLit, tag string, allowed map[string]bool) {
+	report := func(offset int, format string, args ...any) {
+		pos := charPos(lit, tag, offset)
+		pass.Report(analysis.Diagnostic{Pos: pos, End: pos + 1, Message: fmt.Sprintf(format, args...)})
+	}
+
+	seen := map[string]bool{}
+	for i := 0; i < len(tag); {
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+		if i == len(tag) {
+			break
+		}
+
+		start := i
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		key := tag[start:i]
+		switch {
+		case key == "":
+			report(i, "struct tag %q: expected a key, found %q", tag, tag[i])
+			return
+		case i == len(tag):
+			report(start, "struct tag %q: key %q has no value; use %s:\"...\"", tag, key, key)
+			return
+		case tag[i] != ':':
+			report(i, "struct tag %q: expected ':' after key %q, found %q", tag, key, tag[i])
+			return
+		}
+		i++
+
+		if i == len(tag) || tag[i] != '"' {
+			found := "end of tag"
+			if i < len(tag) {
+				found = strconv.QuoteRune(rune(tag[i]))
+			}
+			report(i, "struct tag %q: expected '\"' right after %s:, found %s", tag, key, found)
+			return
+		}
+		quote := i
+		i++
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			report(quote, "struct tag %q: value of %s is missing its closing quote", tag, key)
+			return
+		}
+		i++
+		if _, err := strconv.Unquote(tag[quote:i]); err != nil {
+			report(quote, "struct tag %q: value of %s is not a valid string: %v", tag, key, err)
+			return
+		}
+		if i < len(tag) && tag[i] != ' ' {
+			report(i, "struct tag %q: expected a space between pairs, found %q", tag, tag[i])
+			return
+		}
+
+		if seen[key] {
+			report(start, "struct tag %q: duplicate key %s", tag, key)
+		}
+		seen[key] = true
+		if len(allowed) > 0 && !allowed[key] {
+			report(start, "struct tag %q: unknown key %s", tag, key)
+		}
+	}
+}
+
+// charPos returns the position of byte offset in the unquoted tag. It's exact
+// for raw string literals and for quoted ones without escapes; otherwise it
+// falls back to the start of the literal.
+func charPos(lit *ast.BasicLit, tag string, offset int) token.Pos {
+	if strings.HasPrefix(lit.Value, "`") || lit.Value[1:len(lit.Value)-1] == tag {
+		return lit.Pos() + 1 + token.Pos(offset)
+	}
+	return lit.Pos()
+}
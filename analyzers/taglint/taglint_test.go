@@ -0,0 +1,20 @@
+package taglint_test
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+
+	"github.com/raproid/go-training/analyzers/taglint"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), taglint.Analyzer, "a")
+}
+
+func TestKeys(t *testing.T) {
+	flag := taglint.Analyzer.Flags.Lookup("keys")
+	defer flag.Value.Set(flag.DefValue)
+	flag.Value.Set("sql")
+	analysistest.Run(t, analysistest.TestData(), taglint.Analyzer, "keys")
+}
@@ -0,0 +1,24 @@
+package a
+
+type Mammal struct {
+	Name   string `required max: "100"` // want `struct tag "required max: \\"100\\"": expected ':' after key "required", found ' '`
+	Origin string
+}
+
+type Cat struct {
+	Name     string `json:"name" validate:"required,max=100"`
+	Age      int    `json:"age,omitempty" db:"age"`
+	Owner    string `json:"owner" json:"owner_name"` // want `duplicate key json`
+	Color    string `colour:"ginger"`                // want `unknown key colour`
+	Sound    string `json: "meow"`                   // want `expected '"' right after json:, found ' '`
+	Food     string `json:food`                      // want `expected '"' right after json:, found 'f'`
+	Toys     string `json:"ball`                     // want `value of json is missing its closing quote`
+	Lives    int    `json:"lives"db:"lives"`         // want `expected a space between pairs, found 'd'`
+	Whiskers int    `json`                           // want `key "json" has no value; use json:"..."`
+	Tail     bool   `:"long"`                        // want `expected a key, found ':'`
+	Paws     int    "json:\"paws\" xml:\"paws\""
+	Claws    int    "json:\"claws\"  yaml:claws" // want `expected '"' right after yaml:, found 'c'`
+	Fur      string `json:"fur" `
+	Ears     string `json:"ears\x"` // want `value of json is not a valid string`
+	Nose     string ``
+}
@@ -0,0 +1,6 @@
+package keys
+
+type Row struct {
+	ID   int    `sql:"id" json:"id"` // want `unknown key json`
+	Name string `sql:"name"`
+}
@@ -0,0 +1,88 @@
+package a
+
+import (
+	"fmt"
+	"log"
+	"os"
+)
+
+func typeSwitch(j interface{}) {
+	switch j.(type) {
+	case int:
+		fmt.Println("j is an integer")
+		break
+		fmt.Println("This prints too") // want `unreachable code after break at line 13`
+	case float64:
+		fmt.Println("j is a float")
+	}
+}
+
+func panicker() {
+	fmt.Println("about to panic")
+	defer func() {
+		if err := recover(); err != nil {
+			log.Println("Error:", err)
+		}
+	}()
+	panic("panicking")
+	fmt.Println("done panicking") // want `unreachable code after panic at line 27`
+}
+
+func fatal(err error) {
+	if err != nil {
+		log.Fatal(err)
+		fmt.Println("not printed") // want `unreachable code after log.Fatal at line 33`
+		fmt.Println("reported once with the line above")
+	}
+	os.Exit(1)
+	return // want `unreachable code after os.Exit at line 37`
+}
+
+func fail(msg string) {
+	panic(msg)
+}
+
+func helper() int {
+	fail("always")
+	return 1 // want `unreachable code after fail at line 46`
+}
+
+func loops(xs []int) {
+	for {
+		if len(xs) > 0 {
+			continue
+			xs = xs[1:] // want `unreachable code after continue at line 53`
+		}
+		break
+	}
+outer:
+	for range xs {
+		for range xs {
+			break outer
+			fmt.Println() // want `unreachable code after break outer at line 61`
+		}
+	}
+	for {
+	}
+	fmt.Println("never") // want `unreachable code after endless for loop at line 65`
+}
+
+func branches(ok bool) int {
+	if ok {
+		return 1
+	} else {
+		return 2
+	}
+	fmt.Println("after an if that always returns") // want `^unreachable code$`
+	return 0
+}
+
+func reachable(ok bool) int {
+	if ok {
+		return 1
+	}
+	go func() {
+		return
+	}()
+	return 0
+}
@@ -0,0 +1,182 @@
+// Package unreachable defines an Analyzer that reports statements that can
+// never execute.
+//
+// The type switch lesson has
+//
+//	break
+//	fmt.Println("This prints too")
+//
+// and panicker.go prints "done panicking" right after panic(). The analyzer
+// builds the control flow graph of every function, so it also knows about
+// calls that never return, like log.Fatal, os.Exit or a helper that always
+// panics, and reports each unreachable run of statements once, together with
+// the statement that ends the flow.
+package unreachable
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/ctrlflow"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/cfg"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+const Doc = `report statements that can never execute
+
+A statement after break, continue, goto, return, panic or a call that never
+returns (log.Fatal, os.Exit, ...) is dead code. Each unreachable run of
+statements is reported once, spanning the whole run.`
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "unreachable",
+	Doc:      Doc,
+	Requires: []*analysis.Analyzer{inspect.Analyzer, ctrlflow.Analyzer},
+	Run:      run,
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	cfgs := pass.ResultOf[ctrlflow.Analyzer].(*ctrlflow.CFGs)
+
+	inspect.Preorder([]ast.Node{(*ast.FuncDecl)(nil), (*ast.FuncLit)(nil)}, func(n ast.Node) {
+		var g *cfg.CFG
+		var body *ast.BlockStmt
+		switch fn := n.(type) {
+		case *ast.FuncDecl:
+			g, body = cfgs.FuncDecl(fn), fn.Body
+		case *ast.FuncLit:
+			g, body = cfgs.FuncLit(fn), fn.Body
+		}
+		if g == nil || body == nil {
+			return
+		}
+		c := &checker{pass: pass, cfgs: cfgs, live: map[ast.Node]bool{}}
+		for _, b := range g.Blocks {
+			for _, node := range b.Nodes {
+				c.nodes = append(c.nodes, node)
+				c.live[node] = b.Live
+			}
+		}
+		c.check(body)
+	})
+	return nil, nil
+}
+
+type checker struct {
+	pass  *analysis.Pass
+	cfgs  *ctrlflow.CFGs
+	nodes []ast.Node        // every node of the function's CFG
+	live  map[ast.Node]bool // whether the block holding the node is reachable
+}
+
+// check walks the statement lists of body, reporting the dead runs and not
+// descending into them. Function literals have their own CFG and are skipped.
+func (c *checker) check(body *ast.BlockStmt) {
+	dead := map[ast.Stmt]bool{}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.BlockStmt:
+			c.checkList(n.List, dead)
+		case *ast.CaseClause:
+			c.checkList(n.Body, dead)
+		case *ast.CommClause:
+			c.checkList(n.Body, dead)
+		case ast.Stmt:
+			return !dead[n]
+		}
+		return true
+	})
+}
+
+func (c *checker) checkList(list []ast.Stmt, dead map[ast.Stmt]bool) {
+	for i := 0; i < len(list); i++ {
+		if !c.isDead(list[i]) {
+			continue
+		}
+		first := i
+		for i+1 < len(list) && (c.isDead(list[i+1]) || !c.hasNodes(list[i+1])) {
+			i++
+		}
+		for _, s := range list[first : i+1] {
+			dead[s] = true
+		}
+		msg := "unreachable code"
+		if first > 0 {
+			if cause := c.describe(list[first-1]); cause != "" {
+				msg = fmt.Sprintf("unreachable code after %s at line %d", cause, c.pass.Fset.Position(list[first-1].Pos()).Line)
+			}
+		}
+		c.pass.Report(analysis.Diagnostic{Pos: list[first].Pos(), End: list[i].End(), Message: msg})
+	}
+}
+
+// isDead reports whether stmt has CFG nodes and none of them is reachable.
+func (c *checker) isDead(stmt ast.Stmt) bool {
+	found := false
+	for _, n := range c.nodes {
+		if n.Pos() >= stmt.Pos() && n.End() <= stmt.End() {
+			if c.live[n] {
+				return false
+			}
+			found = true
+		}
+	}
+	return found
+}
+
+func (c *checker) hasNodes(stmt ast.Stmt) bool {
+	for _, n := range c.nodes {
+		if n.Pos() >= stmt.Pos() && n.End() <= stmt.End() {
+			return true
+		}
+	}
+	return false
+}
+
+// describe names the statement that ends the flow, or returns "" if it's
+// something more involved, like an if whose branches all return.
+func (c *checker) describe(stmt ast.Stmt) string {
+	switch s := stmt.(type) {
+	case *ast.ReturnStmt:
+		return "return"
+	case *ast.BranchStmt:
+		if s.Label != nil {
+			return s.Tok.String() + " " + s.Label.Name
+		}
+		return s.Tok.String()
+	case *ast.ExprStmt:
+		call, ok := ast.Unparen(s.X).(*ast.CallExpr)
+		if !ok {
+			return ""
+		}
+		if id, ok := ast.Unparen(call.Fun).(*ast.Ident); ok {
+			if b, ok := c.pass.TypesInfo.Uses[id].(*types.Builtin); ok && b.Name() == "panic" {
+				return "panic"
+			}
+		}
+		if fn := typeutil.StaticCallee(c.pass.TypesInfo, call); fn != nil && c.cfgs.NoReturn(fn) {
+			if fn.Pkg() != nil && fn.Pkg() != c.pass.Pkg {
+				return fn.Pkg().Name() + "." + fn.Name()
+			}
+			return fn.Name()
+		}
+	case *ast.ForStmt:
+		if s.Cond == nil {
+			return "endless for loop"
+		}
+	case *ast.SelectStmt:
+		if len(s.Body.List) == 0 {
+			return "empty select"
+		}
+	case *ast.LabeledStmt:
+		return c.describe(s.Stmt)
+	}
+	return ""
+}
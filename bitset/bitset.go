@@ -0,0 +1,411 @@
+// Package bitset implements sets of non-negative integers as bit vectors of
+// any size.
+//
+// It's the role byte from the bit shifting lesson without the 8-bit limit:
+//
+//	var roles bitset.BitSet
+//	roles.Set(0)               // isAdmin
+//	roles.Set(5)               // canSeeEurope
+//	fmt.Printf("%b\n", &roles) // 100001
+//	roles.Test(1)              // isHeadquarters: false
+//
+// The bits are numbered rather than masks like 1 << iota, so a set can hold
+// thousands of permissions or feature flags.
+//
+// Bit i is stored in word i/64 at position i%64. The zero value is an empty
+// set ready to use, and the set grows as bits are set. Indexes must be
+// non-negative; the methods panic otherwise.
+package bitset
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"iter"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+const wordSize = 64
+
+// BitSet is a set of non-negative integers.
+type BitSet struct {
+	words []uint64
+}
+
+// New returns an empty set with room for bits 0 to n-1 before it has to grow.
+func New(n int) *BitSet {
+	return &BitSet{words: make([]uint64, 0, (n+wordSize-1)/wordSize)}
+}
+
+// Of returns a set holding the given bits.
+func Of(indexes ...int) *BitSet {
+	b := &BitSet{}
+	for _, i := range indexes {
+		b.Set(i)
+	}
+	return b
+}
+
+// FromWords returns a set whose bits are those of the words, lowest word
+// first, so FromWords(uint64(roles)) turns a role byte into a set.
+func FromWords(words ...uint64) *BitSet {
+	b := &BitSet{words: append([]uint64(nil), words...)}
+	b.trim()
+	return b
+}
+
+// Words returns the words of b, lowest first, without trailing zero words.
+func (b *BitSet) Words() []uint64 {
+	return append([]uint64(nil), b.words...)
+}
+
+func check(i int) {
+	if i < 0 {
+		panic("bitset: negative index " + strconv.Itoa(i))
+	}
+}
+
+// grow makes sure word w exists.
+func (b *BitSet) grow(w int) {
+	if w >= len(b.words) {
+		b.words = append(b.words, make([]uint64, w+1-len(b.words))...)
+	}
+}
+
+// trim drops trailing zero words, so that equal sets have equal words.
+func (b *BitSet) trim() {
+	n := len(b.words)
+	for n > 0 && b.words[n-1] == 0 {
+		n--
+	}
+	b.words = b.words[:n]
+}
+
+// Set adds i to the set.
+func (b *BitSet) Set(i int) {
+	check(i)
+	b.grow(i / wordSize)
+	b.words[i/wordSize] |= 1 << (i % wordSize)
+}
+
+// Clear removes i from the set.
+func (b *BitSet) Clear(i int) {
+	check(i)
+	if i/wordSize < len(b.words) {
+		b.words[i/wordSize] &^= 1 << (i % wordSize)
+		b.trim()
+	}
+}
+
+// Toggle flips bit i and reports whether it's set now.
+func (b *BitSet) Toggle(i int) bool {
+	check(i)
+	b.grow(i / wordSize)
+	b.words[i/wordSize] ^= 1 << (i % wordSize)
+	set := b.words[i/wordSize]&(1<<(i%wordSize)) != 0
+	b.trim()
+	return set
+}
+
+// Test reports whether i is in the set.
+func (b *BitSet) Test(i int) bool {
+	check(i)
+	return i/wordSize < len(b.words) && b.words[i/wordSize]&(1<<(i%wordSize)) != 0
+}
+
+// Count returns the number of bits set, the population count.
+func (b *BitSet) Count() int {
+	n := 0
+	for _, w := range b.words {
+		n += bits.OnesCount64(w)
+	}
+	return n
+}
+
+// Len returns the index of the highest set bit plus one, 0 for an empty set.
+func (b *BitSet) Len() int {
+	if len(b.words) == 0 {
+		return 0
+	}
+	last := len(b.words) - 1
+	return last*wordSize + bits.Len64(b.words[last])
+}
+
+// Empty reports whether no bit is set.
+func (b *BitSet) Empty() bool {
+	return len(b.words) == 0
+}
+
+// Equal reports whether b and o hold the same bits.
+func (b *BitSet) Equal(o *BitSet) bool {
+	if len(b.words) != len(o.words) {
+		return false
+	}
+	for i, w := range b.words {
+		if w != o.words[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Clone returns a copy of b.
+func (b *BitSet) Clone() *BitSet {
+	return FromWords(b.words...)
+}
+
+// Union returns a new set with the bits that are in b or o.
+func (b *BitSet) Union(o *BitSet) *BitSet {
+	long, short := b.words, o.words
+	if len(short) > len(long) {
+		long, short = short, long
+	}
+	words := append([]uint64(nil), long...)
+	for i, w := range short {
+		words[i] |= w
+	}
+	return &BitSet{words: words}
+}
+
+// Intersect returns a new set with the bits that are in both b and o.
+func (b *BitSet) Intersect(o *BitSet) *BitSet {
+	words := make([]uint64, min(len(b.words), len(o.words)))
+	for i := range words {
+		words[i] = b.words[i] & o.words[i]
+	}
+	r := &BitSet{words: words}
+	r.trim()
+	return r
+}
+
+// Difference returns a new set with the bits of b that aren't in o, b &^ o.
+func (b *BitSet) Difference(o *BitSet) *BitSet {
+	words := append([]uint64(nil), b.words...)
+	for i := range min(len(words), len(o.words)) {
+		words[i] &^= o.words[i]
+	}
+	r := &BitSet{words: words}
+	r.trim()
+	return r
+}
+
+// IsSubset reports whether every bit of b is also in o, the want&roles == want
+// check of the lesson.
+func (b *BitSet) IsSubset(o *BitSet) bool {
+	return b.Difference(o).Empty()
+}
+
+// NextSet returns the lowest set bit that is at least i, and false if there's none.
+func (b *BitSet) NextSet(i int) (int, bool) {
+	check(i)
+	w := i / wordSize
+	if w >= len(b.words) {
+		return 0, false
+	}
+	word := b.words[w] >> (i % wordSize)
+	if word != 0 {
+		return i + bits.TrailingZeros64(word), true
+	}
+	for w++; w < len(b.words); w++ {
+		if b.words[w] != 0 {
+			return w*wordSize + bits.TrailingZeros64(b.words[w]), true
+		}
+	}
+	return 0, false
+}
+
+// All iterates over the set bits in increasing order. Changing b during the
+// iteration is allowed; the iteration continues after the last bit returned.
+func (b *BitSet) All() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for i, ok := b.NextSet(0); ok; i, ok = b.NextSet(i + 1) {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+}
+
+// Rank returns the number of set bits below i.
+func (b *BitSet) Rank(i int) int {
+	check(i)
+	n := 0
+	for w := 0; w < len(b.words) && w < i/wordSize; w++ {
+		n += bits.OnesCount64(b.words[w])
+	}
+	if w := i / wordSize; w < len(b.words) && i%wordSize != 0 {
+		n += bits.OnesCount64(b.words[w] << (wordSize - i%wordSize))
+	}
+	return n
+}
+
+// Select returns the k-th set bit, counting from 0, so that Rank(Select(k)) == k;
+// it returns false if fewer than k+1 bits are set.
+func (b *BitSet) Select(k int) (int, bool) {
+	if k < 0 {
+		return 0, false
+	}
+	for w, word := range b.words {
+		n := bits.OnesCount64(word)
+		if k >= n {
+			k -= n
+			continue
+		}
+		for ; k > 0; k-- {
+			word &= word - 1 // clear the lowest set bit
+		}
+		return w*wordSize + bits.TrailingZeros64(word), true
+	}
+	return 0, false
+}
+
+// String returns the bits as a set, like "{0 2 5}".
+func (b *BitSet) String() string {
+	var s strings.Builder
+	s.WriteByte('{')
+	for i := range b.All() {
+		if s.Len() > 1 {
+			s.WriteByte(' ')
+		}
+		s.WriteString(strconv.Itoa(i))
+	}
+	s.WriteByte('}')
+	return s.String()
+}
+
+// Binary returns the bits highest first, as %b prints the role byte; an empty
+// set is "0".
+func (b *BitSet) Binary() string {
+	if b.Empty() {
+		return "0"
+	}
+	var s strings.Builder
+	for i := b.Len() - 1; i >= 0; i-- {
+		if b.Test(i) {
+			s.WriteByte('1')
+		} else {
+			s.WriteByte('0')
+		}
+	}
+	return s.String()
+}
+
+// Hex returns the bits as a hexadecimal number in lowercase; an empty set is "0".
+func (b *BitSet) Hex() string {
+	if b.Empty() {
+		return "0"
+	}
+	last := len(b.words) - 1
+	var s strings.Builder
+	s.WriteString(strconv.FormatUint(b.words[last], 16))
+	for i := last - 1; i >= 0; i-- {
+		fmt.Fprintf(&s, "%016x", b.words[i])
+	}
+	return s.String()
+}
+
+// Format implements fmt.Formatter: %b and %x/%X print the set as a number,
+// %v and %s as String does. Width and flags are applied as for strings.
+func (b *BitSet) Format(f fmt.State, verb rune) {
+	var s string
+	switch verb {
+	case 'b':
+		s = b.Binary()
+	case 'x':
+		s = b.Hex()
+	case 'X':
+		s = strings.ToUpper(b.Hex())
+	case 'v', 's':
+		s = b.String()
+	default:
+		fmt.Fprintf(f, "%%!%c(bitset.BitSet=%s)", verb, b.String())
+		return
+	}
+	if f.Flag('#') {
+		switch verb {
+		case 'b':
+			s = "0b" + s
+		case 'x':
+			s = "0x" + s
+		case 'X':
+			s = "0X" + s
+		}
+	}
+	fmt.Fprintf(f, fmt.FormatString(f, 's'), s)
+}
+
+// Parse reads a number written in the given base (2 or 16, say) with the lowest
+// bit last, as Binary and Hex write it. Underscores may separate digits.
+func Parse(s string, base int) (*BitSet, error) {
+	if base < 2 || base > 36 || bits.OnesCount(uint(base)) != 1 {
+		return nil, fmt.Errorf("bitset: base %d is not a power of two", base)
+	}
+	s = strings.ReplaceAll(s, "_", "")
+	if s == "" {
+		return nil, errors.New("bitset: empty number")
+	}
+	width := bits.TrailingZeros(uint(base)) // bits per digit
+	b := &BitSet{}
+	for i := range len(s) {
+		d, err := strconv.ParseUint(s[len(s)-1-i:len(s)-i], base, 8)
+		if err != nil {
+			return nil, fmt.Errorf("bitset: invalid base %d digit %q in %q", base, s[len(s)-1-i], s)
+		}
+		for j := range width {
+			if d&(1<<j) != 0 {
+				b.Set(i*width + j)
+			}
+		}
+	}
+	return b, nil
+}
+
+// binaryVersion is the first byte of the MarshalBinary encoding.
+const binaryVersion = 1
+
+// MarshalBinary encodes b compactly: a version byte and the words in
+// little-endian order, without the trailing zero bytes.
+func (b *BitSet) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 1, 1+len(b.words)*8)
+	data[0] = binaryVersion
+	for _, w := range b.words {
+		data = binary.LittleEndian.AppendUint64(data, w)
+	}
+	for len(data) > 1 && data[len(data)-1] == 0 {
+		data = data[:len(data)-1]
+	}
+	return data, nil
+}
+
+// UnmarshalBinary decodes the MarshalBinary encoding into b.
+func (b *BitSet) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 || data[0] != binaryVersion {
+		return errors.New("bitset: unknown binary encoding")
+	}
+	data = data[1:]
+	b.words = make([]uint64, (len(data)+7)/8)
+	for i := range b.words {
+		var buf [8]byte
+		copy(buf[:], data[i*8:])
+		b.words[i] = binary.LittleEndian.Uint64(buf[:])
+	}
+	b.trim()
+	return nil
+}
+
+// MarshalText encodes b as Hex does, so sets are short hex strings in JSON.
+func (b *BitSet) MarshalText() ([]byte, error) {
+	return []byte(b.Hex()), nil
+}
+
+// UnmarshalText decodes a hexadecimal number into b.
+func (b *BitSet) UnmarshalText(text []byte) error {
+	r, err := Parse(string(text), 16)
+	if err != nil {
+		return err
+	}
+	*b = *r
+	return nil
+}
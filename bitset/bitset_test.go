@@ -0,0 +1,229 @@
+package bitset
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// boundaries has bits on both sides of the first two word boundaries.
+var boundaries = Of(0, 62, 63, 64, 65, 127, 128, 200)
+
+func TestRank(t *testing.T) {
+	tests := []struct {
+		i, want int
+	}{
+		{0, 0}, {1, 1}, {62, 1}, {63, 2}, {64, 3}, {65, 4}, {66, 5},
+		{127, 5}, {128, 6}, {129, 7}, {192, 7}, {200, 7}, {201, 8}, {1000, 8},
+	}
+	for _, tt := range tests {
+		if got := boundaries.Rank(tt.i); got != tt.want {
+			t.Errorf("Rank(%d) = %d, want %d", tt.i, got, tt.want)
+		}
+	}
+	var empty BitSet
+	for _, i := range []int{0, 64, 1000} {
+		if got := empty.Rank(i); got != 0 {
+			t.Errorf("empty set: Rank(%d) = %d, want 0", i, got)
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	want := []int{0, 62, 63, 64, 65, 127, 128, 200}
+	for k, bit := range want {
+		got, ok := boundaries.Select(k)
+		if !ok || got != bit {
+			t.Errorf("Select(%d) = %d, %v, want %d", k, got, ok, bit)
+		}
+		if r := boundaries.Rank(got); r != k {
+			t.Errorf("Rank(Select(%d)) = %d", k, r)
+		}
+	}
+	for _, k := range []int{-1, len(want), 1000} {
+		if got, ok := boundaries.Select(k); ok {
+			t.Errorf("Select(%d) = %d, want false", k, got)
+		}
+	}
+}
+
+func TestNextSet(t *testing.T) {
+	tests := []struct {
+		i, want int
+		ok      bool
+	}{
+		{0, 0, true}, {1, 62, true}, {64, 64, true}, {66, 127, true},
+		{129, 200, true}, {201, 0, false}, {5000, 0, false},
+	}
+	for _, tt := range tests {
+		if got, ok := boundaries.NextSet(tt.i); got != tt.want || ok != tt.ok {
+			t.Errorf("NextSet(%d) = %d, %v, want %d, %v", tt.i, got, ok, tt.want, tt.ok)
+		}
+	}
+	if got := slices.Collect(boundaries.All()); !slices.Equal(got, []int{0, 62, 63, 64, 65, 127, 128, 200}) {
+		t.Errorf("All() = %v", got)
+	}
+}
+
+func TestSetOperations(t *testing.T) {
+	a, b := Of(1, 64, 130), Of(64, 65)
+	tests := []struct {
+		name string
+		got  *BitSet
+		want string
+	}{
+		{"union", a.Union(b), "{1 64 65 130}"},
+		{"intersect", a.Intersect(b), "{64}"},
+		{"difference", a.Difference(b), "{1 130}"},
+		{"cleared", func() *BitSet { c := a.Clone(); c.Clear(130); return c }(), "{1 64}"},
+	}
+	for _, tt := range tests {
+		if s := tt.got.String(); s != tt.want {
+			t.Errorf("%s = %s, want %s", tt.name, s, tt.want)
+		}
+	}
+	if c := a.Clone(); !c.Equal(a) || c.Toggle(1) || c.Equal(a) {
+		t.Error("a clone with a bit toggled off is still equal to the original")
+	}
+	if cleared := Of(130).Intersect(Of(1)); len(cleared.words) != 0 || !cleared.Equal(&BitSet{}) {
+		t.Errorf("an empty result keeps %d words", len(cleared.words))
+	}
+	if !Of(64).IsSubset(a) || a.IsSubset(b) {
+		t.Error("IsSubset")
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	for _, b := range []*BitSet{{}, Of(0), Of(7, 8), Of(63), Of(64), boundaries, FromWords(0, 0, 1<<63)} {
+		data, err := b.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got BitSet
+		if err := got.UnmarshalBinary(data); err != nil {
+			t.Errorf("%v: UnmarshalBinary: %v", b, err)
+		} else if !got.Equal(b) {
+			t.Errorf("binary round trip of %v gives %v", b, &got)
+		}
+
+		j, err := json.Marshal(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = BitSet{}
+		if err := json.Unmarshal(j, &got); err != nil {
+			t.Errorf("%v: json.Unmarshal(%s): %v", b, j, err)
+		} else if !got.Equal(b) {
+			t.Errorf("JSON round trip of %v through %s gives %v", b, j, &got)
+		}
+	}
+
+	if data, _ := Of(7, 8).MarshalBinary(); string(data) != "\x01\x80\x01" {
+		t.Errorf("MarshalBinary of {7 8} = %q, want the trailing zero bytes dropped", data)
+	}
+	if j, _ := json.Marshal(Of(0, 5)); string(j) != `"21"` {
+		t.Errorf("JSON of {0 5} = %s, want \"21\"", j)
+	}
+	for _, data := range []string{"", "\x02\x01"} {
+		if err := new(BitSet).UnmarshalBinary([]byte(data)); err == nil {
+			t.Errorf("UnmarshalBinary(%q): no error", data)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		s    string
+		base int
+		want string
+	}{
+		{"100001", 2, "{0 5}"},
+		{"0", 2, "{}"},
+		{"1_0000_0000", 2, "{8}"},
+		{"ff", 16, "{0 1 2 3 4 5 6 7}"},
+		{"1" + fmt.Sprintf("%016x", 0), 16, "{64}"},
+		{"A", 16, "{1 3}"},
+		{"17", 8, "{0 1 2 3}"},
+		{"v", 32, "{0 1 2 3 4}"},
+	}
+	for _, tt := range tests {
+		b, err := Parse(tt.s, tt.base)
+		if err != nil {
+			t.Errorf("Parse(%q, %d): %v", tt.s, tt.base, err)
+			continue
+		}
+		if b.String() != tt.want {
+			t.Errorf("Parse(%q, %d) = %v, want %s", tt.s, tt.base, b, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		s    string
+		base int
+		want string
+	}{
+		{"101", 10, "bitset: base 10 is not a power of two"},
+		{"101", 64, "bitset: base 64 is not a power of two"},
+		{"101", 1, "bitset: base 1 is not a power of two"},
+		{"", 2, "bitset: empty number"},
+		{"__", 16, "bitset: empty number"},
+		{"102", 2, `bitset: invalid base 2 digit '2' in "102"`},
+		{"0x1f", 16, `bitset: invalid base 16 digit 'x' in "0x1f"`},
+		{"-1", 16, `bitset: invalid base 16 digit '-' in "-1"`},
+	}
+	for _, tt := range tests {
+		b, err := Parse(tt.s, tt.base)
+		if err == nil {
+			t.Errorf("Parse(%q, %d) = %v, want an error", tt.s, tt.base, b)
+			continue
+		}
+		if err.Error() != tt.want {
+			t.Errorf("Parse(%q, %d): %q, want %q", tt.s, tt.base, err, tt.want)
+		}
+	}
+	if err := new(BitSet).UnmarshalText([]byte("xyz")); err == nil {
+		t.Error("UnmarshalText of a non-hex string: no error")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	roles := Of(0, 5)
+	tests := []struct {
+		format string
+		b      *BitSet
+		want   string
+	}{
+		{"%b", roles, "100001"},
+		{"%#b", roles, "0b100001"},
+		{"%10b", roles, "    100001"},
+		{"%-10b|", roles, "100001    |"},
+		{"%x", Of(4, 64), "10000000000000010"},
+		{"%#x", Of(0, 5), "0x21"},
+		{"%X", Of(1, 3, 5, 7), "AA"},
+		{"%#X", Of(1, 3, 5, 7), "0XAA"},
+		{"%v", roles, "{0 5}"},
+		{"%s", roles, "{0 5}"},
+		{"%8v", roles, "   {0 5}"},
+		{"%.3s", roles, "{0 "},
+		{"%b", &BitSet{}, "0"},
+		{"%#x", &BitSet{}, "0x0"},
+		{"%d", roles, "%!d(bitset.BitSet={0 5})"},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprintf(tt.format, tt.b); got != tt.want {
+			t.Errorf("Sprintf(%q, %v) = %q, want %q", tt.format, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestNegativeIndex(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Set(-1) didn't panic")
+		}
+	}()
+	new(BitSet).Set(-1)
+}
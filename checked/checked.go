@@ -0,0 +1,205 @@
+// Package checked does integer arithmetic that reports overflow instead of
+// wrapping around.
+//
+// The integer lessons in test.go show both ways plain arithmetic goes wrong:
+// a uint16 silently wraps from 65535 to 0, and dividing by zero panics (the
+// demo is commented out for that reason). The functions here return an error
+// wrapping ErrOverflow or ErrDivisionByZero instead:
+//
+//	var n uint16 = 65535
+//	sum, err := checked.Add(n, 1) // 0, checked: 65535 + 1 overflows uint16
+//
+// and the Saturating variants clamp the result to the range of the type:
+//
+//	checked.SaturatingAdd(n, 1) // 65535
+//
+// Every function works with all integer types, including named ones.
+package checked
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+)
+
+// Integer is the set of integer types.
+type Integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+var (
+	ErrOverflow       = errors.New("integer overflow")
+	ErrDivisionByZero = errors.New("integer division by zero")
+)
+
+// Error describes the operation that failed.
+type Error struct {
+	Op   string // "+", "-", "*", "/" or "convert"
+	X, Y any    // the operands; Y is nil for conversions
+	Type string // the type of the result
+	Err  error  // ErrOverflow or ErrDivisionByZero
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.Op == "convert":
+		return fmt.Sprintf("checked: %v (%T) doesn't fit in %s", e.X, e.X, e.Type)
+	case e.Err == ErrDivisionByZero:
+		return fmt.Sprintf("checked: %v / 0: division by zero", e.X)
+	}
+	return fmt.Sprintf("checked: %v %s %v overflows %s", e.X, e.Op, e.Y, e.Type)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func overflow[T Integer](op string, x, y T) error {
+	return &Error{Op: op, X: x, Y: y, Type: fmt.Sprintf("%T", x), Err: ErrOverflow}
+}
+
+// signed reports whether T is a signed type.
+func signed[T Integer]() bool {
+	return ^T(0) < 0
+}
+
+// Bounds returns the smallest and the largest value of T.
+func Bounds[T Integer]() (lo, hi T) {
+	if !signed[T]() {
+		return 0, ^T(0)
+	}
+	var zero T
+	bits := unsafe.Sizeof(zero) * 8
+	hi = T(uint64(1)<<(bits-1) - 1)
+	return -hi - 1, hi
+}
+
+// Add returns x + y, or an error if the sum doesn't fit in T.
+func Add[T Integer](x, y T) (T, error) {
+	r := x + y
+	if (r < x) != (y < 0) {
+		return r, overflow("+", x, y)
+	}
+	return r, nil
+}
+
+// Sub returns x - y, or an error if the difference doesn't fit in T.
+func Sub[T Integer](x, y T) (T, error) {
+	r := x - y
+	if (r > x) != (y < 0) {
+		return r, overflow("-", x, y)
+	}
+	return r, nil
+}
+
+// Mul returns x * y, or an error if the product doesn't fit in T.
+func Mul[T Integer](x, y T) (T, error) {
+	if x == 0 || y == 0 {
+		return 0, nil
+	}
+	r := x * y
+	lo, _ := Bounds[T]()
+	minusOne := ^T(0) // only -1 for signed types
+	if signed[T]() && (x == minusOne && y == lo || y == minusOne && x == lo) {
+		return r, overflow("*", x, y) // lo * -1 == lo, and lo / -1 would overflow too
+	}
+	if r/y != x {
+		return r, overflow("*", x, y)
+	}
+	return r, nil
+}
+
+// Div returns x / y, truncated toward zero like the / operator, or an error
+// if y is 0 or the quotient doesn't fit in T (the smallest signed value
+// divided by -1).
+func Div[T Integer](x, y T) (T, error) {
+	if y == 0 {
+		return 0, &Error{Op: "/", X: x, Y: y, Type: fmt.Sprintf("%T", x), Err: ErrDivisionByZero}
+	}
+	lo, _ := Bounds[T]()
+	if signed[T]() && x == lo && y == ^T(0) { // ^T(0) is -1
+		return lo, overflow("/", x, y)
+	}
+	return x / y, nil
+}
+
+// Convert returns x as a To, or an error if the value doesn't fit.
+func Convert[To, From Integer](x From) (To, error) {
+	r := To(x)
+	if From(r) != x || (r < 0) != (x < 0) {
+		var zero To
+		return r, &Error{Op: "convert", X: x, Type: fmt.Sprintf("%T", zero), Err: ErrOverflow}
+	}
+	return r, nil
+}
+
+// SaturatingAdd returns x + y, clamped to the range of T.
+func SaturatingAdd[T Integer](x, y T) T {
+	r, err := Add(x, y)
+	if err == nil {
+		return r
+	}
+	lo, hi := Bounds[T]()
+	if y < 0 {
+		return lo
+	}
+	return hi
+}
+
+// SaturatingSub returns x - y, clamped to the range of T.
+func SaturatingSub[T Integer](x, y T) T {
+	r, err := Sub(x, y)
+	if err == nil {
+		return r
+	}
+	lo, hi := Bounds[T]()
+	if y < 0 {
+		return hi
+	}
+	return lo
+}
+
+// SaturatingMul returns x * y, clamped to the range of T.
+func SaturatingMul[T Integer](x, y T) T {
+	r, err := Mul(x, y)
+	if err == nil {
+		return r
+	}
+	lo, hi := Bounds[T]()
+	if (x < 0) != (y < 0) {
+		return lo
+	}
+	return hi
+}
+
+// SaturatingDiv returns x / y, clamped to the range of T. Dividing by zero
+// saturates like a division by a tiny number would: to the largest value for
+// a positive x, the smallest for a negative one, and 0 for 0.
+func SaturatingDiv[T Integer](x, y T) T {
+	r, err := Div(x, y)
+	if err == nil {
+		return r
+	}
+	lo, hi := Bounds[T]()
+	switch {
+	case errors.Is(err, ErrOverflow), x > 0:
+		return hi
+	case x < 0:
+		return lo
+	}
+	return 0
+}
+
+// SaturatingConvert returns x as a To, clamped to the range of To.
+func SaturatingConvert[To, From Integer](x From) To {
+	r, err := Convert[To](x)
+	if err == nil {
+		return r
+	}
+	lo, hi := Bounds[To]()
+	if x < 0 {
+		return lo
+	}
+	return hi
+}
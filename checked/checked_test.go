@@ -0,0 +1,311 @@
+package checked
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+type celsius int16 // a named type, like the ones Integer allows with ~
+
+func TestBounds(t *testing.T) {
+	tests := []struct {
+		name   string
+		got    [2]any
+		lo, hi any
+	}{
+		{"int", bounds[int](), math.MinInt, math.MaxInt},
+		{"int8", bounds[int8](), int8(math.MinInt8), int8(math.MaxInt8)},
+		{"int16", bounds[int16](), int16(math.MinInt16), int16(math.MaxInt16)},
+		{"int32", bounds[int32](), int32(math.MinInt32), int32(math.MaxInt32)},
+		{"int64", bounds[int64](), int64(math.MinInt64), int64(math.MaxInt64)},
+		{"uint", bounds[uint](), uint(0), uint(math.MaxUint)},
+		{"uint8", bounds[uint8](), uint8(0), uint8(math.MaxUint8)},
+		{"uint16", bounds[uint16](), uint16(0), uint16(math.MaxUint16)},
+		{"uint32", bounds[uint32](), uint32(0), uint32(math.MaxUint32)},
+		{"uint64", bounds[uint64](), uint64(0), uint64(math.MaxUint64)},
+		{"uintptr", bounds[uintptr](), uintptr(0), ^uintptr(0)},
+		{"celsius", bounds[celsius](), celsius(math.MinInt16), celsius(math.MaxInt16)},
+	}
+	for _, tt := range tests {
+		if tt.got != [2]any{tt.lo, tt.hi} {
+			t.Errorf("Bounds[%s]() = %v, %v, want %v, %v", tt.name, tt.got[0], tt.got[1], tt.lo, tt.hi)
+		}
+	}
+}
+
+func bounds[T Integer]() [2]any {
+	lo, hi := Bounds[T]()
+	return [2]any{lo, hi}
+}
+
+// TestEdges checks every operation at the edges of the range of every integer
+// kind.
+func TestEdges(t *testing.T) {
+	t.Run("int", testEdges[int])
+	t.Run("int8", testEdges[int8])
+	t.Run("int16", testEdges[int16])
+	t.Run("int32", testEdges[int32])
+	t.Run("int64", testEdges[int64])
+	t.Run("uint", testEdges[uint])
+	t.Run("uint8", testEdges[uint8])
+	t.Run("uint16", testEdges[uint16])
+	t.Run("uint32", testEdges[uint32])
+	t.Run("uint64", testEdges[uint64])
+	t.Run("uintptr", testEdges[uintptr])
+	t.Run("celsius", testEdges[celsius])
+}
+
+func testEdges[T Integer](t *testing.T) {
+	lo, hi := Bounds[T]()
+	minusOne := ^T(0) // only -1 for signed types; the largest value otherwise
+	isSigned := minusOne < 0
+	two, three, seven := T(2), T(3), T(7) // negated below, which untyped constants can't be for unsigned T
+
+	type op struct {
+		name       string
+		f          func(x, y T) (T, error)
+		saturating func(x, y T) T
+	}
+	add := op{"Add", Add[T], SaturatingAdd[T]}
+	sub := op{"Sub", Sub[T], SaturatingSub[T]}
+	mul := op{"Mul", Mul[T], SaturatingMul[T]}
+	div := op{"Div", Div[T], SaturatingDiv[T]}
+
+	tests := []struct {
+		op      op
+		x, y    T
+		want    T
+		wantErr error // the saturating variant returns want regardless
+	}{
+		{add, hi - 1, 1, hi, nil},
+		{add, hi, 0, hi, nil},
+		{add, hi, 1, hi, ErrOverflow},
+		{add, hi, hi, hi, ErrOverflow},
+		{add, lo, 0, lo, nil},
+		{sub, lo + 1, 1, lo, nil},
+		{sub, lo, 0, lo, nil},
+		{sub, lo, 1, lo, ErrOverflow},
+		{sub, hi, hi, 0, nil},
+		{mul, hi, 1, hi, nil},
+		{mul, hi, 0, 0, nil},
+		{mul, 0, hi, 0, nil},
+		{mul, hi, 2, hi, ErrOverflow},
+		{mul, hi/2 + 1, 2, hi, ErrOverflow},
+		{mul, hi / 2, 2, hi - 1, nil},
+		{mul, lo, 1, lo, nil},
+		{div, hi, 1, hi, nil},
+		{div, lo, 1, lo, nil},
+		{div, hi, hi, 1, nil},
+		{div, 0, hi, 0, nil},
+		{div, hi, 0, hi, ErrDivisionByZero},
+		{div, 0, 0, 0, ErrDivisionByZero},
+	}
+	if isSigned {
+		tests = append(tests, []struct {
+			op      op
+			x, y    T
+			want    T
+			wantErr error
+		}{
+			{add, lo, minusOne, lo, ErrOverflow},
+			{add, lo, hi, minusOne, nil},
+			{sub, hi, minusOne, hi, ErrOverflow},
+			{sub, minusOne, hi, lo, nil},
+			{sub, 0, lo, hi, ErrOverflow},
+			{sub, minusOne, lo, hi, nil},
+			{mul, lo, minusOne, hi, ErrOverflow},
+			{mul, minusOne, lo, hi, ErrOverflow},
+			{mul, hi, minusOne, -hi, nil},
+			{mul, lo, 2, lo, ErrOverflow},
+			{mul, lo, -two, hi, ErrOverflow},
+			{mul, lo / 2, 2, lo, nil},
+			{div, lo, minusOne, hi, ErrOverflow},
+			{div, hi, minusOne, -hi, nil},
+			{div, lo, 2, lo / 2, nil},
+			{div, -seven, two, -three, nil}, // truncated toward zero
+			{div, lo, 0, lo, ErrDivisionByZero},
+		}...)
+	}
+
+	for _, tt := range tests {
+		got, err := tt.op.f(tt.x, tt.y)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s(%v, %v) error = %v, want %v", tt.op.name, tt.x, tt.y, err, tt.wantErr)
+		} else if err == nil && got != tt.want {
+			t.Errorf("%s(%v, %v) = %v, want %v", tt.op.name, tt.x, tt.y, got, tt.want)
+		}
+		if got := tt.op.saturating(tt.x, tt.y); got != tt.want {
+			t.Errorf("Saturating%s(%v, %v) = %v, want %v", tt.op.name, tt.x, tt.y, got, tt.want)
+		}
+	}
+}
+
+func TestError(t *testing.T) {
+	var n uint16 = 65535
+	sum, err := Add(n, 1)
+	if sum != 0 || err == nil || err.Error() != "checked: 65535 + 1 overflows uint16" {
+		t.Errorf("Add(uint16(65535), 1) = %v, %v", sum, err)
+	}
+	var e *Error
+	if !errors.As(err, &e) || e.Op != "+" || e.X != n || e.Y != uint16(1) || e.Type != "uint16" || e.Err != ErrOverflow {
+		t.Errorf("Add(uint16(65535), 1) error = %#v", e)
+	}
+
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{second(Sub(int8(-128), 1)), "checked: -128 - 1 overflows int8"},
+		{second(Mul(celsius(200), 200)), "checked: 200 * 200 overflows checked.celsius"},
+		{second(Div(7, 0)), "checked: 7 / 0: division by zero"},
+		{second(Div(int32(math.MinInt32), -1)), "checked: -2147483648 / -1 overflows int32"},
+		{second(Convert[uint8](-1)), "checked: -1 (int) doesn't fit in uint8"},
+	}
+	for _, tt := range tests {
+		if tt.err == nil || tt.err.Error() != tt.want {
+			t.Errorf("error = %v, want %q", tt.err, tt.want)
+		}
+	}
+	if !errors.Is(second(Div(1, 0)), ErrDivisionByZero) || errors.Is(second(Div(1, 0)), ErrOverflow) {
+		t.Error("a division by zero isn't ErrDivisionByZero, or is ErrOverflow too")
+	}
+}
+
+func second[T any](_ T, err error) error { return err }
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name      string
+		convert   func() (r, saturated any, err error)
+		want      any // nil if the conversion fails
+		saturated any
+	}{
+		{"int8(-1) to uint8", conv[uint8](int8(-1)), nil, uint8(0)},
+		{"int8(-128) to uint64", conv[uint64](int8(-128)), nil, uint64(0)},
+		{"int8(127) to uint8", conv[uint8](int8(127)), uint8(127), uint8(127)},
+		{"uint8(128) to int8", conv[int8](uint8(128)), nil, int8(math.MaxInt8)},
+		{"uint8(127) to int8", conv[int8](uint8(127)), int8(127), int8(127)},
+		{"int(-1) to uint", conv[uint](-1), nil, uint(0)},
+		{"uint max to int", conv[int](uint(math.MaxUint)), nil, int(math.MaxInt)},
+		{"int64 min to uint64", conv[uint64](int64(math.MinInt64)), nil, uint64(0)},
+		{"uint64 max to int64", conv[int64](uint64(math.MaxUint64)), nil, int64(math.MaxInt64)},
+		{"int64 max to uint64", conv[uint64](int64(math.MaxInt64)), uint64(math.MaxInt64), uint64(math.MaxInt64)},
+		{"uint64 1<<63 to int64", conv[int64](uint64(1 << 63)), nil, int64(math.MaxInt64)},
+		{"int64 1<<31 to int32", conv[int32](int64(1 << 31)), nil, int32(math.MaxInt32)},
+		{"int64 -1<<31 to int32", conv[int32](int64(-1 << 31)), int32(math.MinInt32), int32(math.MinInt32)},
+		{"int64 -1<<31-1 to int32", conv[int32](int64(-1<<31 - 1)), nil, int32(math.MinInt32)},
+		{"uint32 max to uint16", conv[uint16](uint32(math.MaxUint32)), nil, uint16(math.MaxUint16)},
+		{"uintptr 1 to int8", conv[int8](uintptr(1)), int8(1), int8(1)},
+		{"int16 -300 to celsius", conv[celsius](int16(-300)), celsius(-300), celsius(-300)},
+		{"celsius -300 to uint16", conv[uint16](celsius(-300)), nil, uint16(0)},
+	}
+	for _, tt := range tests {
+		got, saturated, err := tt.convert()
+		switch {
+		case tt.want == nil && !errors.Is(err, ErrOverflow):
+			t.Errorf("Convert %s = %v, %v, want ErrOverflow", tt.name, got, err)
+		case tt.want != nil && (err != nil || got != tt.want):
+			t.Errorf("Convert %s = %v, %v, want %v", tt.name, got, err, tt.want)
+		}
+		if saturated != tt.saturated {
+			t.Errorf("SaturatingConvert %s = %v, want %v", tt.name, saturated, tt.saturated)
+		}
+	}
+}
+
+func conv[To, From Integer](x From) func() (any, any, error) {
+	return func() (any, any, error) {
+		r, err := Convert[To](x)
+		return r, SaturatingConvert[To](x), err
+	}
+}
+
+// clamp returns r as a T, clamped to its range, and whether it fits.
+func clamp[T Integer](r int64) (T, bool) {
+	lo, hi := Bounds[T]()
+	switch {
+	case r < int64(lo):
+		return lo, false
+	case r > int64(hi):
+		return hi, false
+	}
+	return T(r), true
+}
+
+// TestExhaustive8 checks every operation on every pair of int8 and of uint8
+// values against arithmetic in int64, which can't overflow for them.
+func TestExhaustive8(t *testing.T) {
+	t.Run("int8", exhaustive[int8])
+	t.Run("uint8", exhaustive[uint8])
+}
+
+func exhaustive[T int8 | uint8](t *testing.T) {
+	lo, hi := Bounds[T]()
+	ops := []struct {
+		name       string
+		f          func(x, y T) (T, error)
+		saturating func(x, y T) T
+		wide       func(x, y int64) int64
+	}{
+		{"Add", Add[T], SaturatingAdd[T], func(x, y int64) int64 { return x + y }},
+		{"Sub", Sub[T], SaturatingSub[T], func(x, y int64) int64 { return x - y }},
+		{"Mul", Mul[T], SaturatingMul[T], func(x, y int64) int64 { return x * y }},
+		{"Div", Div[T], SaturatingDiv[T], func(x, y int64) int64 { return x / y }},
+	}
+	for x := int64(lo); x <= int64(hi); x++ {
+		for y := int64(lo); y <= int64(hi); y++ {
+			for _, op := range ops {
+				got, err := op.f(T(x), T(y))
+				sat := op.saturating(T(x), T(y))
+				if op.name == "Div" && y == 0 {
+					want := T(0)
+					if x > 0 {
+						want = hi
+					} else if x < 0 {
+						want = lo
+					}
+					if !errors.Is(err, ErrDivisionByZero) || sat != want {
+						t.Fatalf("%s(%d, 0) = %v, %v; saturating %v, want %v", op.name, x, got, err, sat, want)
+					}
+					continue
+				}
+				want, fits := clamp[T](op.wide(x, y))
+				if fits != (err == nil) || fits && got != want {
+					t.Fatalf("%s(%d, %d) = %v, %v, want %v (fits: %v)", op.name, x, y, got, err, want, fits)
+				}
+				if !fits && !errors.Is(err, ErrOverflow) {
+					t.Fatalf("%s(%d, %d) error = %v, want ErrOverflow", op.name, x, y, err)
+				}
+				if sat != want {
+					t.Fatalf("Saturating%s(%d, %d) = %v, want %v", op.name, x, y, sat, want)
+				}
+			}
+		}
+	}
+}
+
+// TestExhaustiveConvert converts every int16 value to the 8-bit types and
+// back and compares with the range check done in int64.
+func TestExhaustiveConvert(t *testing.T) {
+	for x := int64(math.MinInt16); x <= math.MaxInt16; x++ {
+		checkConvert[int8](t, int16(x))
+		checkConvert[uint8](t, int16(x))
+		checkConvert[int8](t, uint16(x))
+		checkConvert[uint8](t, uint16(x))
+		checkConvert[int16](t, uint16(x))
+		checkConvert[uint16](t, int16(x))
+	}
+}
+
+func checkConvert[To Integer, From int16 | uint16](t *testing.T, x From) {
+	t.Helper()
+	want, fits := clamp[To](int64(x))
+	got, err := Convert[To](x)
+	if fits != (err == nil) || fits && got != want {
+		t.Fatalf("Convert[%T](%T(%d)) = %v, %v, want %v (fits: %v)", want, x, x, got, err, want, fits)
+	}
+	if sat := SaturatingConvert[To](x); sat != want {
+		t.Fatalf("SaturatingConvert[%T](%T(%d)) = %v, want %v", want, x, x, sat, want)
+	}
+}
@@ -0,0 +1,424 @@
+// Command bitcalc evaluates bitwise expressions and shows every step in
+// binary, hexadecimal and decimal.
+//
+// Usage:
+//
+//	bitcalc [-type int] expression...
+//
+// The expression uses Go syntax: integer literals (8, 0x1f, 0b1010, 1_000),
+// parentheses, the unary operators ^ and -, and the binary operators &, |, ^,
+// &^, << and >>. Every value has the type given with -type, one of int8,
+// int16, int32, int64, uint8, uint16, uint32, uint64 or their aliases int,
+// uint, byte and rune. The AND/OR/shift lesson in test.go, at 8 bits:
+//
+//	$ bitcalc -type uint8 '8 & 7 | 8 >> 3 | 8 << 3'
+//
+// An expression that starts with - needs a -- before it, so that it isn't
+// taken for a flag: bitcalc -type int8 -- '-128 >> 3'.
+//
+// Shifts that lose set bits or change the sign, literals that don't fit the
+// type and negations that wrap around are flagged as overflow; a >> on a
+// negative signed value is flagged as sign extension, and the bits it filled
+// in are marked with ^ under the binary value.
+//
+// As in Go, the count of a shift is an untyped constant: it doesn't have the
+// -type, so uint8 values can be shifted by 256, and it must not be negative.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/parser"
+	"go/token"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// intType is the integer type every value of the expression has.
+type intType struct {
+	name   string
+	bits   int
+	signed bool
+}
+
+var types = map[string]intType{}
+
+func init() {
+	for _, t := range []intType{
+		{"int8", 8, true}, {"int16", 16, true}, {"int32", 32, true}, {"int64", 64, true},
+		{"uint8", 8, false}, {"uint16", 16, false}, {"uint32", 32, false}, {"uint64", 64, false},
+	} {
+		types[t.name] = t
+	}
+	types["int"], types["uint"] = types["int64"], types["uint64"]
+	types["byte"], types["rune"] = types["uint8"], types["int32"]
+}
+
+func (t intType) mask() uint64 {
+	return ^uint64(0) >> (64 - t.bits)
+}
+
+func (t intType) signBit() uint64 {
+	return 1 << (t.bits - 1)
+}
+
+// int returns the value of the bits x, sign-extended for signed types.
+func (t intType) int(x uint64) int64 {
+	if t.signed && x&t.signBit() != 0 {
+		return int64(x | ^t.mask())
+	}
+	return int64(x)
+}
+
+func (t intType) decimal(x uint64) string {
+	if t.signed {
+		return strconv.FormatInt(t.int(x), 10)
+	}
+	return strconv.FormatUint(x, 10)
+}
+
+// binary returns the bits of x with a space between the bytes.
+func (t intType) binary(x uint64) string {
+	s := fmt.Sprintf("%0*b", t.bits, x)
+	var b strings.Builder
+	for i := 0; i < len(s); i += 8 {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(s[i : i+8])
+	}
+	return b.String()
+}
+
+func (t intType) hex(x uint64) string {
+	return fmt.Sprintf("0x%0*x", t.bits/4, x)
+}
+
+// step is one evaluated subexpression.
+type step struct {
+	expr  string
+	value uint64
+	note  string // overflow or sign extension, if any
+	marks uint64 // bits to point out under the binary value
+}
+
+type calc struct {
+	t     intType
+	steps []step
+}
+
+func main() {
+	typeName := flag.String("type", "int", "integer type of the values: int8 to int64, uint8 to uint64, int, uint, byte or rune")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: bitcalc [-type int] expression...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	t, ok := types[*typeName]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "bitcalc: unknown type %q\n", *typeName)
+		os.Exit(2)
+	}
+
+	src := strings.Join(flag.Args(), " ")
+	expr, err := parser.ParseExpr(src)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "bitcalc:", err)
+		os.Exit(1)
+	}
+	c := &calc{t: t}
+	if _, err := c.eval(expr); err != nil {
+		fmt.Fprintln(os.Stderr, "bitcalc:", err)
+		os.Exit(1)
+	}
+	c.print()
+}
+
+// eval evaluates e, recording a step for every literal and operation.
+func (c *calc) eval(e ast.Expr) (uint64, error) {
+	t := c.t
+	switch e := e.(type) {
+	case *ast.ParenExpr:
+		return c.eval(e.X)
+
+	case *ast.BasicLit:
+		return c.literal(e.Value, false)
+
+	case *ast.UnaryExpr:
+		if lit, ok := e.X.(*ast.BasicLit); ok && e.Op == token.SUB {
+			return c.literal(lit.Value, true) // -128 is a literal that fits int8, 128 alone isn't
+		}
+		x, err := c.eval(e.X)
+		if err != nil {
+			return 0, err
+		}
+		s := step{expr: render(e)}
+		switch e.Op {
+		case token.XOR:
+			s.value = ^x & t.mask()
+		case token.SUB:
+			s.value = -x & t.mask()
+			if t.signed && x == t.signBit() {
+				s.note = fmt.Sprintf("overflow: -(%s) doesn't fit in %s and wraps around to itself", t.decimal(x), t.name)
+			} else if !t.signed && x != 0 {
+				s.note = fmt.Sprintf("overflow: %s is unsigned, so -%d wraps around", t.name, x)
+			}
+		case token.ADD:
+			s.value = x
+		default:
+			return 0, fmt.Errorf("unsupported operator %s in %s", e.Op, s.expr)
+		}
+		c.steps = append(c.steps, s)
+		return s.value, nil
+
+	case *ast.BinaryExpr:
+		x, err := c.eval(e.X)
+		if err != nil {
+			return 0, err
+		}
+		s := step{expr: render(e)}
+		if e.Op == token.SHL || e.Op == token.SHR {
+			n, err := shiftCount(e.Y)
+			if err != nil {
+				return 0, fmt.Errorf("%v in %s", err, s.expr)
+			}
+			if e.Op == token.SHL {
+				c.shiftLeft(&s, x, n)
+			} else {
+				c.shiftRight(&s, x, n)
+			}
+			c.steps = append(c.steps, s)
+			return s.value, nil
+		}
+		y, err := c.eval(e.Y)
+		if err != nil {
+			return 0, err
+		}
+		switch e.Op {
+		case token.AND:
+			s.value = x & y
+		case token.OR:
+			s.value = x | y
+		case token.XOR:
+			s.value = x ^ y
+		case token.AND_NOT:
+			s.value = x &^ y
+		default:
+			return 0, fmt.Errorf("unsupported operator %s in %s; bitcalc only knows &, |, ^, &^, << and >>", e.Op, s.expr)
+		}
+		c.steps = append(c.steps, s)
+		return s.value, nil
+	}
+	return 0, fmt.Errorf("unsupported expression %s; use integer literals and operators", render(e))
+}
+
+// maxShift bounds the left shifts inside a shift count, which are evaluated
+// exactly and would otherwise make arbitrarily large numbers.
+const maxShift = 1024
+
+// shiftCount evaluates the count of a shift. As in Go, it is an untyped
+// constant that must not be negative, whatever the type of the value shifted,
+// so its literals aren't limited to -type and aren't shown as steps.
+func shiftCount(e ast.Expr) (uint64, error) {
+	v, err := untyped(e)
+	if err != nil {
+		return 0, err
+	}
+	if constant.Sign(v) < 0 {
+		return 0, fmt.Errorf("negative shift count %s", v)
+	}
+	n, exact := constant.Uint64Val(v)
+	if !exact {
+		return 0, fmt.Errorf("shift count %s too large", v)
+	}
+	return n, nil
+}
+
+// untyped evaluates e as an untyped integer constant.
+func untyped(e ast.Expr) (constant.Value, error) {
+	switch e := e.(type) {
+	case *ast.ParenExpr:
+		return untyped(e.X)
+
+	case *ast.BasicLit:
+		v := constant.MakeFromLiteral(e.Value, e.Kind, 0)
+		if e.Kind != token.INT || v.Kind() != constant.Int {
+			return nil, fmt.Errorf("invalid integer literal %s", e.Value)
+		}
+		return v, nil
+
+	case *ast.UnaryExpr:
+		x, err := untyped(e.X)
+		if err != nil {
+			return nil, err
+		}
+		switch e.Op {
+		case token.XOR, token.SUB, token.ADD:
+			return constant.UnaryOp(e.Op, x, 0), nil
+		}
+		return nil, fmt.Errorf("unsupported operator %s in %s", e.Op, render(e))
+
+	case *ast.BinaryExpr:
+		x, err := untyped(e.X)
+		if err != nil {
+			return nil, err
+		}
+		switch e.Op {
+		case token.SHL, token.SHR:
+			n, err := shiftCount(e.Y)
+			if err != nil {
+				return nil, err
+			}
+			if e.Op == token.SHL && n > maxShift {
+				return nil, fmt.Errorf("shift count %d too large", n)
+			}
+			return constant.Shift(x, e.Op, uint(n)), nil
+		case token.AND, token.OR, token.XOR, token.AND_NOT:
+			y, err := untyped(e.Y)
+			if err != nil {
+				return nil, err
+			}
+			return constant.BinaryOp(x, e.Op, y), nil
+		}
+		return nil, fmt.Errorf("unsupported operator %s in %s; bitcalc only knows &, |, ^, &^, << and >>", e.Op, render(e))
+	}
+	return nil, fmt.Errorf("unsupported expression %s; use integer literals and operators", render(e))
+}
+
+// literal parses an integer literal and records it, flagging literals that
+// don't fit the type.
+func (c *calc) literal(lit string, negative bool) (uint64, error) {
+	t := c.t
+	n, err := strconv.ParseUint(lit, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid integer literal %s", lit)
+	}
+	s := step{expr: lit}
+	if negative {
+		s.expr = "-" + lit
+	}
+
+	fits := n <= t.mask()
+	switch {
+	case t.signed && negative:
+		fits = n <= t.signBit()
+	case t.signed:
+		fits = n < t.signBit()
+	case negative:
+		fits = n == 0
+	}
+	if negative {
+		n = -n
+	}
+	s.value = n & t.mask()
+	if !fits {
+		s.note = fmt.Sprintf("overflow: %s doesn't fit in %s, only the low %d bits are kept", s.expr, t.name, t.bits)
+	}
+	c.steps = append(c.steps, s)
+	return s.value, nil
+}
+
+func (c *calc) shiftLeft(s *step, x, n uint64) {
+	t := c.t
+	if n >= uint64(t.bits) {
+		s.value = 0
+	} else {
+		s.value = x << n & t.mask()
+	}
+	// shifting back must give x again, otherwise bits (or the sign) were lost
+	var back uint64
+	if n < uint64(t.bits) {
+		back = s.value >> n
+		if t.signed {
+			back = uint64(t.int(s.value)>>n) & t.mask()
+		}
+	}
+	switch {
+	case back == x:
+	case t.signed && t.int(x) < 0 != (t.int(s.value) < 0):
+		s.note = fmt.Sprintf("overflow: the sign changed, %s << %d is not %s × 2^%d", t.decimal(x), n, t.decimal(x), n)
+	default:
+		lost := x
+		if n < uint64(t.bits) {
+			lost = x >> (uint64(t.bits) - n)
+		}
+		s.note = fmt.Sprintf("overflow: set bits were shifted out of the %d bits", t.bits)
+		if lost != 0 {
+			s.note += fmt.Sprintf(" (0b%b lost)", lost)
+		}
+	}
+}
+
+func (c *calc) shiftRight(s *step, x, n uint64) {
+	t := c.t
+	negative := t.signed && x&t.signBit() != 0
+	switch {
+	case n >= uint64(t.bits) && negative:
+		s.value = t.mask()
+	case n >= uint64(t.bits):
+		s.value = 0
+	case negative:
+		s.value = uint64(t.int(x)>>n) & t.mask()
+	default:
+		s.value = x >> n
+	}
+	if negative && n > 0 {
+		filled := min(n, uint64(t.bits))
+		s.marks = t.mask() &^ (t.mask() >> filled)
+		s.note = fmt.Sprintf("sign extension: %s is signed and negative, so >> fills the top %d bits with 1s", t.name, filled)
+	}
+}
+
+func (c *calc) print() {
+	t := c.t
+	width := 0
+	for _, s := range c.steps {
+		width = max(width, len(s.expr))
+	}
+	binHeader := "binary (" + t.name + ")"
+	binWidth, hexWidth := max(len(t.binary(0)), len(binHeader)), max(len(t.hex(0)), len("hex"))
+	fmt.Printf("%-*s  %-*s  %-*s  %s\n", width, "", binWidth, binHeader, hexWidth, "hex", "decimal")
+	for _, s := range c.steps {
+		fmt.Printf("%-*s  %-*s  %-*s  %s", width, s.expr, binWidth, t.binary(s.value), hexWidth, t.hex(s.value), t.decimal(s.value))
+		if s.note != "" {
+			fmt.Printf("  <- %s", s.note)
+		}
+		fmt.Println()
+		if s.marks != 0 {
+			marks := strings.Map(func(r rune) rune {
+				switch r {
+				case '1':
+					return '^'
+				case '0':
+					return ' '
+				}
+				return r
+			}, t.binary(s.marks))
+			fmt.Printf("%-*s  %s\n", width, "", strings.TrimRight(marks, " "))
+		}
+	}
+}
+
+// render prints e as written, normalized to gofmt spacing.
+func render(e ast.Expr) string {
+	switch e := e.(type) {
+	case *ast.BasicLit:
+		return e.Value
+	case *ast.ParenExpr:
+		return "(" + render(e.X) + ")"
+	case *ast.UnaryExpr:
+		return e.Op.String() + render(e.X)
+	case *ast.BinaryExpr:
+		return render(e.X) + " " + e.Op.String() + " " + render(e.Y)
+	case *ast.Ident:
+		return e.Name
+	}
+	return fmt.Sprintf("%T", e)
+}
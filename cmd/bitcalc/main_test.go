@@ -0,0 +1,103 @@
+package main
+
+import (
+	"go/parser"
+	"strings"
+	"testing"
+)
+
+// evaluate evaluates src with values of the type named typ and returns the
+// decimal result and the last step.
+func evaluate(t *testing.T, typ, src string) (string, step, error) {
+	t.Helper()
+	expr, err := parser.ParseExpr(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &calc{t: types[typ]}
+	v, err := c.eval(expr)
+	if err != nil {
+		return "", step{}, err
+	}
+	return c.t.decimal(v), c.steps[len(c.steps)-1], nil
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		typ, src string
+		want     string
+		note     string // a prefix of the note of the last step
+	}{
+		{"uint8", "8 & 7 | 8 >> 3 | 8 << 3", "65", ""},
+		{"int", "0b1010 &^ 0x3", "8", ""},
+		{"uint8", "^0", "255", ""},
+		{"uint8", "256", "0", "overflow: 256 doesn't fit"},
+		{"int8", "-128", "-128", ""},
+		{"int8", "-(-128)", "-128", "overflow: -(-128) doesn't fit"},
+		{"uint8", "-1", "255", "overflow: -1 doesn't fit"},
+		{"int8", "1 << 7", "-128", "overflow: the sign changed"},
+		{"uint8", "3 << 7", "128", "overflow: set bits were shifted out"},
+		{"int8", "-128 >> 3", "-16", "sign extension"},
+		{"int8", "-1 >> 100", "-1", "sign extension"},
+
+		// the count is untyped, so it can be larger than -type allows
+		{"uint8", "1 << 256", "0", "overflow: set bits were shifted out"},
+		{"uint8", "1 << 7", "128", ""},
+		{"int8", "1 << 200", "0", "overflow: set bits were shifted out"},
+		{"int8", "64 >> 128", "0", ""},
+		{"uint8", "255 >> (1 << 2)", "15", ""},
+		{"uint8", "1 << (1 << 64 >> 62)", "16", ""},
+		{"uint64", "1 << ^-65", "0", "overflow: set bits were shifted out"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.typ+" "+tt.src, func(t *testing.T) {
+			got, last, err := evaluate(t, tt.typ, tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("= %s, want %s", got, tt.want)
+			}
+			if !strings.HasPrefix(last.note, tt.note) || (tt.note == "") != (last.note == "") {
+				t.Errorf("note %q, want %q", last.note, tt.note)
+			}
+		})
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	tests := []struct {
+		typ, src string
+		want     string
+	}{
+		{"int8", "1 << -1", "negative shift count -1 in 1 << -1"},
+		{"uint8", "1 << ^0", "negative shift count -1"},
+		{"uint8", "1 << (2 &^ 3 - 1)", "unsupported operator -"},
+		{"int", "1 << (1 << 100)", "shift count 1267650600228229401496703205376 too large"},
+		{"int", "1 << (1 << 2000)", "shift count 2000 too large"},
+		{"int", "1 << 1.5", "invalid integer literal 1.5"},
+		{"int", "1 + 2", "unsupported operator +"},
+		{"int", "x & 1", "unsupported expression x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.typ+" "+tt.src, func(t *testing.T) {
+			got, _, err := evaluate(t, tt.typ, tt.src)
+			if err == nil {
+				t.Fatalf("= %s, want an error", got)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %q, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestShiftRightMarks(t *testing.T) {
+	_, last, err := evaluate(t, "int16", "-256 >> 4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := types["int16"].binary(last.marks); got != "11110000 00000000" {
+		t.Errorf("marks %s, want the top 4 bits", got)
+	}
+}
@@ -0,0 +1,272 @@
+// Command check compiles a learner's solution against an exercise's hidden tests.
+//
+// Usage:
+//
+//	check [-dir exercises] [-timeout 30s] <exercise> [solution.go]
+//	check -list
+//
+// The solution defaults to <exercise>.go in the current directory. It is copied
+// together with the exercise's hidden tests into a temporary module and tested
+// with the local Go toolchain; the result of every test case is reported, and a
+// test that doesn't finish, or never starts because an earlier one crashed the
+// test binary, counts as failed.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/raproid/go-training/internal/tempmod"
+)
+
+// hiddenTests is the file in every exercise directory holding the tests the learner doesn't see.
+const hiddenTests = "hidden_test.go"
+
+// testEvent is the subset of a `go test -json` event that check needs.
+type testEvent struct {
+	Action  string
+	Test    string
+	Elapsed float64
+	Output  string
+}
+
+// result is the outcome of a single test case.
+type result struct {
+	name    string
+	action  string
+	elapsed float64
+	output  []string
+}
+
+func main() {
+	dir := flag.String("dir", "exercises", "directory holding the exercises")
+	timeout := flag.Duration("timeout", 30*time.Second, "maximum time for compiling and running the tests")
+	list := flag.Bool("list", false, "list the available exercises and exit")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: check [flags] <exercise> [solution.go]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *list {
+		if err := listExercises(*dir); err != nil {
+			fmt.Fprintln(os.Stderr, "check:", err)
+			os.Exit(2)
+		}
+		return
+	}
+	if flag.NArg() < 1 || flag.NArg() > 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	exercise := flag.Arg(0)
+	solution := exercise + ".go"
+	if flag.NArg() == 2 {
+		solution = flag.Arg(1)
+	}
+
+	ok, err := check(filepath.Join(*dir, exercise), solution, *timeout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "check:", err)
+		os.Exit(2)
+	}
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+// listExercises prints the name of every exercise in dir.
+func listExercises(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, e.Name(), hiddenTests)); err != nil {
+			continue
+		}
+		fmt.Println(e.Name())
+	}
+	return nil
+}
+
+// check runs the hidden tests of the exercise in exerciseDir against solution
+// and reports whether all of them passed.
+func check(exerciseDir, solution string, timeout time.Duration) (bool, error) {
+	tests, err := os.ReadFile(filepath.Join(exerciseDir, hiddenTests))
+	if err != nil {
+		return false, fmt.Errorf("unknown exercise %q: %w", filepath.Base(exerciseDir), err)
+	}
+	src, err := os.ReadFile(solution)
+	if err != nil {
+		return false, fmt.Errorf("reading solution: %w", err)
+	}
+
+	tmp, err := tempmod.Create("exercise", map[string][]byte{
+		"solution.go": src,
+		hiddenTests:   tests,
+	})
+	if err != nil {
+		return false, err
+	}
+	defer os.RemoveAll(tmp)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// listing the tests compiles them, and gives the tests that never get to
+	// run when one of them crashes the test binary
+	list, err := goTest(ctx, tmp, "-list", ".", ".").CombinedOutput()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		fmt.Printf("FAIL: timed out after %v\n", timeout)
+		return false, nil
+	}
+	if err != nil {
+		fmt.Println("FAIL: solution does not compile")
+		fmt.Print(string(list))
+		return false, nil
+	}
+	names := listedTests(list)
+
+	cmd := goTest(ctx, tmp, "-json", "-count=1", "-timeout", timeout.String(), ".")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, runErr := cmd.Output()
+	timedOut := errors.Is(ctx.Err(), context.DeadlineExceeded)
+	if timedOut {
+		fmt.Printf("FAIL: timed out after %v\n", timeout)
+	}
+
+	results, pkgOutput := parseEvents(out)
+	results = addUnfinished(results, names)
+	if len(results) == 0 {
+		fmt.Println("FAIL: no tests ran")
+		fmt.Print(stderr.String())
+		for _, line := range pkgOutput {
+			fmt.Print(line)
+		}
+		return false, nil
+	}
+
+	passed := 0
+	for _, r := range results {
+		fmt.Printf("%-4s %s (%.2fs)\n", strings.ToUpper(r.action), r.name, r.elapsed)
+		switch r.action {
+		case "pass":
+			passed++
+		case "fail":
+			for _, line := range r.output {
+				fmt.Print("     ", line)
+			}
+		}
+	}
+	fmt.Printf("%d/%d passed\n", passed, len(results))
+	if runErr != nil && !timedOut && passed == len(results) {
+		// the tests passed, but the test binary itself failed
+		return false, fmt.Errorf("go test: %v: %s", runErr, stderr.String())
+	}
+	return passed == len(results), nil
+}
+
+// goTest returns a go test command with args, run in dir with the local
+// toolchain.
+func goTest(ctx context.Context, dir string, args ...string) *exec.Cmd {
+	return tempmod.Command(ctx, dir, append([]string{"test"}, args...)...)
+}
+
+// listedTests returns the tests, examples and fuzz targets in the output of
+// `go test -list`; benchmarks don't run without -bench and are left out.
+func listedTests(out []byte) []string {
+	var names []string
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.ContainsAny(line, " \t") {
+			continue // the "ok exercise 0.01s" summary
+		}
+		for _, prefix := range []string{"Test", "Example", "Fuzz"} {
+			if strings.HasPrefix(line, prefix) {
+				names = append(names, line)
+				break
+			}
+		}
+	}
+	return names
+}
+
+// addUnfinished adds a failed result for every listed test that never
+// started, which happens when an earlier test crashes the test binary or the
+// run times out, and keeps the results sorted by name.
+func addUnfinished(results []result, names []string) []result {
+	seen := map[string]bool{}
+	for _, r := range results {
+		seen[r.name] = true
+	}
+	for _, name := range names {
+		if !seen[name] {
+			results = append(results, result{name: name, action: "fail", output: []string{"did not run: the test binary stopped before it got to this test\n"}})
+		}
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].name < results[j].name })
+	return results
+}
+
+// parseEvents turns `go test -json` output into per-test results, sorted by
+// name, and the package-level output lines.
+func parseEvents(out []byte) ([]result, []string) {
+	byName := map[string]*result{}
+	var pkgOutput []string
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		var e testEvent
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			pkgOutput = append(pkgOutput, sc.Text()+"\n")
+			continue
+		}
+		if e.Test == "" {
+			if e.Action == "output" || e.Action == "build-output" {
+				pkgOutput = append(pkgOutput, e.Output)
+			}
+			continue
+		}
+		r, ok := byName[e.Test]
+		if !ok {
+			r = &result{name: e.Test}
+			byName[e.Test] = r
+		}
+		switch e.Action {
+		case "output":
+			if !strings.HasPrefix(strings.TrimSpace(e.Output), "===") && !strings.HasPrefix(strings.TrimSpace(e.Output), "---") {
+				r.output = append(r.output, e.Output)
+			}
+		case "pass", "fail", "skip":
+			r.action = e.Action
+			r.elapsed = e.Elapsed
+		}
+	}
+
+	results := make([]result, 0, len(byName))
+	for _, r := range byName {
+		if r.action == "" {
+			// started but never finished, e.g. the binary panicked or timed out
+			r.action = "fail"
+			r.output = append(r.output, "did not finish\n")
+		}
+		results = append(results, *r)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].name < results[j].name })
+	return results, pkgOutput
+}
@@ -0,0 +1,254 @@
+// Command du reports what takes up the space in a directory tree.
+//
+// Usage:
+//
+//	du [-top 10] [-format text|json|csv] [-follow] [-workers n] [dir]
+//
+// It walks dir (the current directory by default) with several goroutines,
+// sums the sizes per directory and prints the total, the largest files and
+// directories, and how many files fall into the size buckets built from the
+// KB, MB, GB constants of the iota lesson. Symbolic links are counted as
+// links unless -follow is given; followed links that lead back into the tree
+// are walked only once. Unreadable files and directories are reported on
+// stderr and the exit status is 1, but the walk goes on without them.
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+)
+
+// The size constants of the bit shifting lesson in test.go.
+const (
+	_  = iota
+	KB = 1 << (10 * iota)
+	MB
+	GB
+	TB
+)
+
+// bucketLimits name the size buckets; a file goes into the first one its size is below.
+var bucketLimits = [...]struct {
+	name  string
+	below int64
+}{
+	{"<1KB", KB},
+	{"KB", MB},
+	{"MB", GB},
+	{"GB", TB},
+	{"TB", math.MaxInt64},
+}
+
+type bucket struct {
+	files int
+	bytes int64
+}
+
+func (b *bucket) add(size int64) {
+	b.files++
+	b.bytes += size
+}
+
+func bucketOf(size int64) int {
+	for i, l := range bucketLimits {
+		if size < l.below {
+			return i
+		}
+	}
+	return len(bucketLimits) - 1
+}
+
+// report is what gets printed, in every format.
+type report struct {
+	Root         string         `json:"root"`
+	Bytes        int64          `json:"bytes"`
+	Files        int            `json:"files"`
+	Dirs         int            `json:"dirs"`
+	LargestFiles []entry        `json:"largest_files"`
+	LargestDirs  []entry        `json:"largest_dirs"`
+	Buckets      []bucketReport `json:"buckets"`
+	Errors       int            `json:"errors"`
+}
+
+type entry struct {
+	Path  string `json:"path"`
+	Bytes int64  `json:"bytes"`
+	Files int    `json:"files,omitempty"` // for directories, including subdirectories
+}
+
+type bucketReport struct {
+	Name  string `json:"name"`
+	Files int    `json:"files"`
+	Bytes int64  `json:"bytes"`
+}
+
+func main() {
+	top := flag.Int("top", 10, "number of largest files and directories to list")
+	format := flag.String("format", "text", "output format: text, json or csv")
+	follow := flag.Bool("follow", false, "follow symbolic links instead of counting the links themselves")
+	workers := flag.Int("workers", 4*runtime.GOMAXPROCS(0), "maximum number of directories read at the same time")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: du [flags] [dir]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *top < 0 {
+		fmt.Fprintf(os.Stderr, "du: -top %d is negative\n", *top)
+		flag.Usage()
+		os.Exit(2)
+	}
+	root := "."
+	if flag.NArg() == 1 {
+		root = flag.Arg(0)
+	}
+	write, ok := writers[*format]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "du: unknown format %q\n", *format)
+		os.Exit(2)
+	}
+	if info, err := os.Stat(root); err != nil {
+		fmt.Fprintln(os.Stderr, "du:", err)
+		os.Exit(1)
+	} else if !info.IsDir() {
+		fmt.Fprintf(os.Stderr, "du: %s is not a directory\n", root)
+		os.Exit(1)
+	}
+
+	w := newWalker(*workers, *top, *follow)
+	tree := w.run(root)
+	for _, e := range w.errs {
+		fmt.Fprintln(os.Stderr, "du:", e.err)
+	}
+
+	r := summarize(tree, *top)
+	r.Errors = len(w.errs)
+	if err := write(os.Stdout, r); err != nil {
+		fmt.Fprintln(os.Stderr, "du:", err)
+		os.Exit(1)
+	}
+	if len(w.errs) > 0 {
+		os.Exit(1)
+	}
+}
+
+// summarize merges the per-directory results of the walk into a report.
+func summarize(tree *dir, top int) *report {
+	r := &report{Root: tree.path, Bytes: tree.total, Files: tree.totalFiles}
+	var files []file
+	var dirs []*dir
+	var buckets [len(bucketLimits)]bucket
+	tree.all(func(d *dir) {
+		for _, f := range d.largest {
+			files = insertTop(files, f, top)
+		}
+		if d != tree {
+			dirs = append(dirs, d)
+		}
+		for i, b := range d.buckets {
+			buckets[i].files += b.files
+			buckets[i].bytes += b.bytes
+		}
+	})
+	r.Dirs = len(dirs)
+
+	r.LargestFiles = []entry{}
+	for _, f := range files {
+		r.LargestFiles = append(r.LargestFiles, entry{Path: f.path, Bytes: f.size})
+	}
+	slices.SortFunc(dirs, func(a, b *dir) int {
+		return cmp.Or(cmp.Compare(b.total, a.total), cmp.Compare(a.path, b.path))
+	})
+	r.LargestDirs = []entry{}
+	for _, d := range dirs[:min(top, len(dirs))] {
+		r.LargestDirs = append(r.LargestDirs, entry{Path: d.path, Bytes: d.total, Files: d.totalFiles})
+	}
+	for i, b := range buckets {
+		r.Buckets = append(r.Buckets, bucketReport{Name: bucketLimits[i].name, Files: b.files, Bytes: b.bytes})
+	}
+	return r
+}
+
+var writers = map[string]func(io.Writer, *report) error{
+	"text": writeText,
+	"json": writeJSON,
+	"csv":  writeCSV,
+}
+
+func writeText(w io.Writer, r *report) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%10s  %s, %d files in %d directories\n", human(r.Bytes), r.Root, r.Files, r.Dirs)
+	if r.Errors > 0 {
+		fmt.Fprintf(bw, "%10s  %d paths couldn't be read, see above\n", "", r.Errors)
+	}
+	if len(r.LargestFiles) > 0 {
+		fmt.Fprintln(bw, "\nLargest files:")
+		for _, f := range r.LargestFiles {
+			fmt.Fprintf(bw, "%10s  %s\n", human(f.Bytes), f.Path)
+		}
+	}
+	if len(r.LargestDirs) > 0 {
+		fmt.Fprintln(bw, "\nLargest directories:")
+		for _, d := range r.LargestDirs {
+			fmt.Fprintf(bw, "%10s  %s (%d files)\n", human(d.Bytes), d.Path, d.Files)
+		}
+	}
+	fmt.Fprintln(bw, "\nFiles by size:")
+	for _, b := range r.Buckets {
+		fmt.Fprintf(bw, "%10s  %-4s %d files\n", human(b.Bytes), b.Name, b.Files)
+	}
+	return bw.Flush()
+}
+
+func writeJSON(w io.Writer, r *report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// writeCSV writes one table; the kind column tells the rows apart.
+func writeCSV(w io.Writer, r *report) error {
+	cw := csv.NewWriter(w)
+	itoa := func(n int64) string { return strconv.FormatInt(n, 10) }
+	cw.Write([]string{"kind", "path", "bytes", "files"})
+	cw.Write([]string{"total", r.Root, itoa(r.Bytes), strconv.Itoa(r.Files)})
+	for _, f := range r.LargestFiles {
+		cw.Write([]string{"file", f.Path, itoa(f.Bytes), ""})
+	}
+	for _, d := range r.LargestDirs {
+		cw.Write([]string{"dir", d.Path, itoa(d.Bytes), strconv.Itoa(d.Files)})
+	}
+	for _, b := range r.Buckets {
+		cw.Write([]string{"bucket", b.Name, itoa(b.Bytes), strconv.Itoa(b.Files)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// human formats n like the lesson's fileSize/GB, in the largest unit that keeps it at least 1.
+func human(n int64) string {
+	switch {
+	case n >= TB:
+		return fmt.Sprintf("%.2fTB", float64(n)/TB)
+	case n >= GB:
+		return fmt.Sprintf("%.2fGB", float64(n)/GB)
+	case n >= MB:
+		return fmt.Sprintf("%.2fMB", float64(n)/MB)
+	case n >= KB:
+		return fmt.Sprintf("%.2fKB", float64(n)/KB)
+	}
+	return fmt.Sprintf("%dB", n)
+}
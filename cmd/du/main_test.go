@@ -0,0 +1,164 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestBucketOf(t *testing.T) {
+	tests := []struct {
+		size int64
+		want string
+	}{
+		{0, "<1KB"},
+		{KB - 1, "<1KB"},
+		{KB, "KB"},
+		{MB - 1, "KB"},
+		{MB, "MB"},
+		{GB, "GB"},
+		{TB - 1, "GB"},
+		{TB, "TB"},
+		{1 << 62, "TB"},
+	}
+	for _, tt := range tests {
+		if got := bucketLimits[bucketOf(tt.size)].name; got != tt.want {
+			t.Errorf("bucketOf(%d) = %s, want %s", tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestInsertTop(t *testing.T) {
+	sizes := func(list []file) []int64 {
+		var s []int64
+		for _, f := range list {
+			s = append(s, f.size)
+		}
+		return s
+	}
+	tests := []struct {
+		name  string
+		sizes []int64
+		n     int
+		want  []int64
+	}{
+		{"fewer than n", []int64{3, 1, 2}, 5, []int64{3, 2, 1}},
+		{"more than n", []int64{1, 5, 2, 4, 3}, 3, []int64{5, 4, 3}},
+		{"smaller than the rest once full", []int64{5, 4, 1}, 2, []int64{5, 4}},
+		{"ties", []int64{2, 2, 2, 1}, 2, []int64{2, 2}},
+		{"n is 0", []int64{1, 2}, 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var list []file
+			for _, s := range tt.sizes {
+				list = insertTop(list, file{size: s}, tt.n)
+			}
+			if got := sizes(list); !slices.Equal(got, tt.want) {
+				t.Errorf("sizes %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// among files of the same size, the one added last goes first
+	list := insertTop([]file{{"a", 2}, {"b", 1}}, file{"c", 2}, 2)
+	if list[0].path != "c" || list[1].path != "a" {
+		t.Errorf("ties: %v", list)
+	}
+}
+
+// tree builds root with the files under it, given by path and size.
+func tree(t *testing.T, files map[string]int) string {
+	t.Helper()
+	root := t.TempDir()
+	for path, size := range files {
+		path = filepath.Join(root, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestSummarize(t *testing.T) {
+	root := tree(t, map[string]int{
+		"a.txt":          10,
+		"big.bin":        2 * KB,
+		"docs/x.md":      100,
+		"docs/y.md":      300,
+		"docs/old/z.md":  5,
+		"media/clip.mp4": 3 * KB,
+		"media/empty":    0,
+	})
+	rel := func(path string) string {
+		r, _ := filepath.Rel(root, path)
+		return filepath.ToSlash(r)
+	}
+
+	w := newWalker(2, 3, false)
+	r := summarize(w.run(root), 3)
+	if len(w.errs) != 0 {
+		t.Fatalf("errors: %v", w.errs)
+	}
+	if r.Bytes != 10+2*KB+100+300+5+3*KB || r.Files != 7 || r.Dirs != 3 {
+		t.Errorf("total %d bytes, %d files in %d directories", r.Bytes, r.Files, r.Dirs)
+	}
+
+	var files []string
+	for _, f := range r.LargestFiles {
+		files = append(files, rel(f.Path))
+	}
+	if want := []string{"media/clip.mp4", "big.bin", "docs/y.md"}; !slices.Equal(files, want) {
+		t.Errorf("largest files %v, want %v", files, want)
+	}
+
+	var dirs []entry
+	for _, d := range r.LargestDirs {
+		dirs = append(dirs, entry{Path: rel(d.Path), Bytes: d.Bytes, Files: d.Files})
+	}
+	want := []entry{{"media", 3 * KB, 2}, {"docs", 405, 3}, {"docs/old", 5, 1}}
+	if !slices.Equal(dirs, want) {
+		t.Errorf("largest directories %v, want %v", dirs, want)
+	}
+
+	var buckets []bucketReport
+	for _, b := range r.Buckets {
+		if b.Files > 0 {
+			buckets = append(buckets, b)
+		}
+	}
+	if want := []bucketReport{{"<1KB", 5, 415}, {"KB", 2, 5 * KB}}; !slices.Equal(buckets, want) {
+		t.Errorf("buckets %v, want %v", buckets, want)
+	}
+
+	r = summarize(w.run(root), 0)
+	if len(r.LargestFiles) != 0 || len(r.LargestDirs) != 0 || r.Dirs != 3 {
+		t.Errorf("-top 0 lists %v and %v", r.LargestFiles, r.LargestDirs)
+	}
+	if r = summarize(w.run(root), 100); len(r.LargestDirs) != 3 {
+		t.Errorf("-top 100 lists %d directories, want all 3", len(r.LargestDirs))
+	}
+}
+
+func TestHuman(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0B"},
+		{1023, "1023B"},
+		{KB, "1.00KB"},
+		{1536, "1.50KB"},
+		{5 * GB, "5.00GB"},
+		{2 * TB, "2.00TB"},
+	}
+	for _, tt := range tests {
+		if got := human(tt.n); got != tt.want {
+			t.Errorf("human(%d) = %s, want %s", tt.n, got, tt.want)
+		}
+	}
+}
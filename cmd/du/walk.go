@@ -0,0 +1,198 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// dir is a directory of the tree. A dir's fields are only written by the
+// goroutine walking it; children are walked by their own goroutines, so the
+// totals are summed up after the walk.
+type dir struct {
+	path     string
+	depth    int
+	own      int64 // bytes of the files directly inside
+	files    int   // number of files directly inside
+	buckets  [len(bucketLimits)]bucket
+	largest  []file // the top files directly inside, largest first
+	children []*dir
+
+	total      int64 // own plus every subdirectory, set by sum
+	totalFiles int
+}
+
+type file struct {
+	path string
+	size int64
+}
+
+// walkError is a path the walk couldn't read; the walk goes on without it.
+type walkError struct {
+	path string
+	err  error
+}
+
+type walker struct {
+	top    int
+	follow bool // follow symbolic links instead of counting the links themselves
+
+	wg  sync.WaitGroup
+	sem chan struct{} // limits the number of goroutines reading directories
+
+	mu      sync.Mutex
+	errs    []walkError
+	visited map[string]bool // real paths of the directories walked, to stop symlink loops
+}
+
+func newWalker(workers, top int, follow bool) *walker {
+	return &walker{
+		top:     top,
+		follow:  follow,
+		sem:     make(chan struct{}, max(workers, 1)),
+		visited: map[string]bool{},
+	}
+}
+
+// run walks the tree under root and returns it with the totals summed up.
+// Like du -H, a symlink given as the root is followed even without -follow.
+func (w *walker) run(root string) *dir {
+	d := &dir{path: root}
+	if w.enter(root) {
+		w.walk(d)
+	}
+	w.wg.Wait()
+	d.sum()
+	return d
+}
+
+// spawn walks d on a new goroutine if a worker is free and on this one otherwise,
+// so that a deep tree can't block the walk on a full semaphore.
+func (w *walker) spawn(d *dir) {
+	select {
+	case w.sem <- struct{}{}:
+		w.wg.Add(1)
+		go func() {
+			defer func() {
+				<-w.sem
+				w.wg.Done()
+			}()
+			w.walk(d)
+		}()
+	default:
+		w.walk(d)
+	}
+}
+
+func (w *walker) walk(d *dir) {
+	entries, err := os.ReadDir(d.path)
+	if err != nil {
+		w.fail(d.path, err) // entries still holds what could be read before the error
+	}
+	for _, e := range entries {
+		path := filepath.Join(d.path, e.Name())
+		var info fs.FileInfo
+		var err error
+		if e.Type()&fs.ModeSymlink != 0 && w.follow {
+			info, err = os.Stat(path)
+		} else {
+			info, err = e.Info()
+		}
+		if err != nil {
+			w.fail(path, err)
+			continue
+		}
+
+		if info.IsDir() {
+			if w.enter(path) {
+				child := &dir{path: path, depth: d.depth + 1}
+				d.children = append(d.children, child)
+				w.spawn(child)
+			}
+			continue
+		}
+		if !info.Mode().IsRegular() && info.Mode()&fs.ModeSymlink == 0 {
+			continue // devices, sockets and pipes don't take up space
+		}
+		d.add(file{path, info.Size()}, w.top)
+	}
+}
+
+// enter reports whether the directory at path should be walked; with
+// -follow, a directory reached a second time through a symlink isn't.
+func (w *walker) enter(path string) bool {
+	if !w.follow {
+		return true
+	}
+	real, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		w.fail(path, err)
+		return false
+	}
+	real, _ = filepath.Abs(real)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.visited[real] {
+		return false
+	}
+	w.visited[real] = true
+	return true
+}
+
+func (w *walker) fail(path string, err error) {
+	w.mu.Lock()
+	w.errs = append(w.errs, walkError{path, err})
+	w.mu.Unlock()
+}
+
+// add counts f and keeps it if it's among the top largest files of d.
+func (d *dir) add(f file, top int) {
+	d.own += f.size
+	d.files++
+	d.buckets[bucketOf(f.size)].add(f.size)
+	if top > 0 {
+		d.largest = insertTop(d.largest, f, top)
+	}
+}
+
+// sum fills in the totals of d and its subdirectories.
+func (d *dir) sum() {
+	d.total, d.totalFiles = d.own, d.files
+	for _, c := range d.children {
+		c.sum()
+		d.total += c.total
+		d.totalFiles += c.totalFiles
+	}
+}
+
+// all calls fn for d and every directory below it.
+func (d *dir) all(fn func(*dir)) {
+	fn(d)
+	for _, c := range d.children {
+		c.all(fn)
+	}
+}
+
+// insertTop inserts f into list, which is sorted largest first, and drops
+// whatever falls off the end past n.
+func insertTop(list []file, f file, n int) []file {
+	i, _ := slices.BinarySearchFunc(list, f.size, func(e file, size int64) int {
+		switch {
+		case e.size > size:
+			return -1
+		case e.size < size:
+			return 1
+		}
+		return 0
+	})
+	if i >= n {
+		return list
+	}
+	list = slices.Insert(list, i, f)
+	if len(list) > n {
+		list = list[:n]
+	}
+	return list
+}
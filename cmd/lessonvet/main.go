@@ -0,0 +1,41 @@
+// Command lessonvet reports the mistakes the lessons in test.go demonstrate on purpose.
+//
+// It runs on its own:
+//
+//	lessonvet test.go panicker.go
+//
+// or as a vet tool, next to the standard checks:
+//
+//	go vet -vettool=$(which lessonvet) test.go panicker.go
+//
+// The tutorial files are named explicitly because their //go:build ignore
+// line keeps them, and the mistakes in them, out of ./... and go vet ./....
+//
+// Run lessonvet help for the list of analyzers and their flags.
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/multichecker"
+
+	"github.com/raproid/go-training/analyzers/appendalias"
+	"github.com/raproid/go-training/analyzers/deferargs"
+	"github.com/raproid/go-training/analyzers/floatcompare"
+	"github.com/raproid/go-training/analyzers/maporder"
+	"github.com/raproid/go-training/analyzers/printverbs"
+	"github.com/raproid/go-training/analyzers/shadow"
+	"github.com/raproid/go-training/analyzers/taglint"
+	"github.com/raproid/go-training/analyzers/unreachable"
+)
+
+func main() {
+	multichecker.Main(
+		appendalias.Analyzer,
+		deferargs.Analyzer,
+		floatcompare.Analyzer,
+		maporder.Analyzer,
+		printverbs.Analyzer,
+		shadow.Analyzer,
+		taglint.Analyzer,
+		unreachable.Analyzer,
+	)
+}
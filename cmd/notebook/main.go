@@ -0,0 +1,81 @@
+// Command notebook runs the Go cells of a Markdown notebook and stores their output.
+//
+// Usage:
+//
+//	notebook [-cell n] [-timeout 10s] [-stdout] notebook.md
+//
+// Every ```go block is a cell. It is wrapped in a main package, built in its
+// own temporary module with the local Go toolchain and run with a timeout; the
+// output goes into an ```output block right after the cell, and the notebook
+// is rewritten in place (or printed with -stdout). See package notebook for
+// the file format.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/raproid/go-training/notebook"
+)
+
+func main() {
+	cell := flag.Int("cell", 0, "run only this cell, counting from 1; 0 runs all of them")
+	timeout := flag.Duration("timeout", 10*time.Second, "maximum time for building and running a single cell")
+	stdout := flag.Bool("stdout", false, "print the notebook instead of rewriting the file")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: notebook [flags] notebook.md")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := run(ctx, flag.Arg(0), *cell, *timeout, *stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "notebook:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, path string, only int, timeout time.Duration, stdout bool) error {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	nb := notebook.Parse(src)
+	if only < 0 || only > len(nb.Cells) {
+		return fmt.Errorf("%s has %d cells, there's no cell %d", path, len(nb.Cells), only)
+	}
+
+	r := &notebook.Runner{Timeout: timeout}
+	for i, c := range nb.Cells {
+		if only != 0 && i+1 != only {
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "running cell %d (%s:%d)\n", i+1, path, c.Line)
+		if err := r.Run(ctx, c); err != nil {
+			return fmt.Errorf("cell %d: %w", i+1, err)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+
+	if stdout {
+		_, err := os.Stdout.Write(nb.Format())
+		return err
+	}
+	// write next to the notebook and rename, so an interrupted run can't leave it half-written
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, nb.Format(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
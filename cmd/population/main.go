@@ -0,0 +1,193 @@
+// Command population reports how the populations in a CSV file change over
+// the years.
+//
+// Usage:
+//
+//	population [-from year] [-to year] [-project year] [-method linear|exponential|both] file.csv
+//
+// The file holds one series per state (or any other region) in the long or
+// the wide layout of the population package; - reads standard input. For
+// every region the report shows the populations in the -from and -to years
+// (by default the first and the last year all regions have), the compound
+// annual growth between them and the latest year-over-year growth, and, with
+// -project, the projected population in that year. It ends with the changes
+// in the ranking between the two years.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/raproid/go-training/population"
+)
+
+func main() {
+	from := flag.Int("from", 0, "first year to compare; 0 means the earliest year all regions have")
+	to := flag.Int("to", 0, "second year to compare; 0 means the latest year all regions have")
+	project := flag.Int("project", 0, "year to project the populations to; 0 means no projection")
+	method := flag.String("method", "both", "projection method: linear, exponential or both")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: population [flags] file.csv")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	var methods []population.Method
+	if *method == "both" {
+		methods = []population.Method{population.Linear, population.Exponential}
+	} else {
+		m, err := population.ParseMethod(*method)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		methods = []population.Method{m}
+	}
+
+	d, err := read(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if len(d) == 0 {
+		fmt.Fprintln(os.Stderr, "population: no data in", flag.Arg(0))
+		os.Exit(1)
+	}
+	if *from == 0 || *to == 0 {
+		first, last := yearRange(d)
+		if *from == 0 {
+			*from = first
+		}
+		if *to == 0 {
+			*to = last
+		}
+	}
+
+	report(os.Stdout, d, *from, *to, *project, methods)
+}
+
+func read(path string) (population.Dataset, error) {
+	if path == "-" {
+		return population.ReadCSV(os.Stdin)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return population.ReadCSV(f)
+}
+
+// yearRange returns the earliest and the latest year that every series has
+// a value for, or of any series if they don't have two years in common.
+func yearRange(d population.Dataset) (first, last int) {
+	count := map[int]int{}
+	for _, s := range d {
+		for _, p := range s.Points {
+			count[p.Year]++
+		}
+	}
+	for _, common := range []bool{true, false} {
+		first, last = 0, 0
+		for year, n := range count {
+			if common && n < len(d) {
+				continue
+			}
+			if first == 0 || year < first {
+				first = year
+			}
+			last = max(last, year)
+		}
+		if first != last {
+			break
+		}
+	}
+	return first, last
+}
+
+func report(w io.Writer, d population.Dataset, from, to, project int, methods []population.Method) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "region\t%d\t%d\tCAGR\tlatest YoY", from, to)
+	if project != 0 {
+		for _, m := range methods {
+			fmt.Fprintf(tw, "\t%s %d", m, project)
+		}
+	}
+	fmt.Fprintln(tw)
+
+	for _, name := range d.Names() {
+		s := d[name]
+		fmt.Fprintf(tw, "%s\t%s\t%s", name, value(s, from), value(s, to))
+		if rate, err := s.CAGR(from, to); err == nil {
+			fmt.Fprintf(tw, "\t%s", percent(rate))
+		} else {
+			fmt.Fprint(tw, "\t-")
+		}
+		if yoy := s.YearOverYear(); len(yoy) > 0 {
+			g := yoy[len(yoy)-1]
+			fmt.Fprintf(tw, "\t%s (%d)", percent(g.Rate), g.To)
+		} else {
+			fmt.Fprint(tw, "\t-")
+		}
+		if project != 0 {
+			for _, m := range methods {
+				if p, err := s.Project(project, m); err == nil {
+					fmt.Fprintf(tw, "\t%s", thousands(int64(p+0.5)))
+				} else {
+					fmt.Fprint(tw, "\t-")
+				}
+			}
+		}
+		fmt.Fprintln(tw)
+	}
+	tw.Flush()
+
+	changes := d.RankChanges(from, to)
+	if len(changes) == 0 || from == to {
+		return
+	}
+	fmt.Fprintf(w, "\nRanking %d → %d:\n", from, to)
+	tw = tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for _, c := range changes {
+		moved := "="
+		switch {
+		case c.Moved() > 0:
+			moved = "↑" + strconv.Itoa(c.Moved())
+		case c.Moved() < 0:
+			moved = "↓" + strconv.Itoa(-c.Moved())
+		}
+		fmt.Fprintf(tw, "%d.\t%s\t(was %d.)\t%s\n", c.To, c.Name, c.From, moved)
+	}
+	tw.Flush()
+}
+
+func value(s *population.Series, year int) string {
+	if n, ok := s.Value(year); ok {
+		return thousands(n)
+	}
+	return "-"
+}
+
+func percent(rate float64) string {
+	return strconv.FormatFloat(rate*100, 'f', 2, 64) + "%"
+}
+
+// thousands formats n with commas between groups of three digits.
+func thousands(n int64) string {
+	s := strconv.FormatInt(n, 10)
+	neg := ""
+	if n < 0 {
+		neg, s = "-", s[1:]
+	}
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return neg + s
+}
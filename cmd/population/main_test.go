@@ -0,0 +1,92 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/raproid/go-training/population"
+)
+
+func dataset(t *testing.T, src string) population.Dataset {
+	t.Helper()
+	d, err := population.ReadCSV(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestYearRange(t *testing.T) {
+	tests := []struct {
+		name, src   string
+		first, last int
+	}{
+		{"same years", "state,2015,2016,2017\nCA,1,2,3\nTX,1,2,3\n", 2015, 2017},
+		{"common years", "state,2014,2015,2016,2017\nCA,1,2,3,\nTX,,2,3,4\n", 2015, 2016},
+		{"one year in common", "state,2014,2015,2016\nCA,1,2,\nTX,,2,3\n", 2014, 2016},
+		{"nothing in common", "state,2014,2015\nCA,1,\nTX,,2\n", 2014, 2015},
+		{"a single year", "state,2015\nCA,1\n", 2015, 2015},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, last := yearRange(dataset(t, tt.src))
+			if first != tt.first || last != tt.last {
+				t.Errorf("yearRange = %d, %d, want %d, %d", first, last, tt.first, tt.last)
+			}
+		})
+	}
+}
+
+func TestThousands(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0"},
+		{999, "999"},
+		{1000, "1,000"},
+		{39250017, "39,250,017"},
+		{-1234567, "-1,234,567"},
+		{-100, "-100"},
+	}
+	for _, tt := range tests {
+		if got := thousands(tt.n); got != tt.want {
+			t.Errorf("thousands(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	d := dataset(t, "state,2015,2016\nCA,39144818,39250017\nTX,27469114,27862596\nFL,20271272,20612439\nNY,,19745289\n")
+	var b strings.Builder
+	report(&b, d, 2015, 2016, 2020, []population.Method{population.Linear, population.Exponential})
+	want := `region  2015        2016        CAGR   latest YoY    linear 2020  exponential 2020
+CA      39,144,818  39,250,017  0.27%  0.27% (2016)  39,670,813   39,673,648
+FL      20,271,272  20,612,439  1.68%  1.68% (2016)  21,977,107   22,035,500
+NY      -           19,745,289  -      -             -            -
+TX      27,469,114  27,862,596  1.43%  1.43% (2016)  29,436,524   29,493,702
+
+Ranking 2015 → 2016:
+1.  CA  (was 1.)  =
+2.  TX  (was 2.)  =
+3.  FL  (was 3.)  =
+`
+	if b.String() != want {
+		t.Errorf("report:\n%s\nwant\n%s", b.String(), want)
+	}
+
+	d = dataset(t, "state,2000,2010\nCA,30,30\nTX,20,40\n")
+	b.Reset()
+	report(&b, d, 2000, 2010, 0, nil)
+	want = `region  2000  2010  CAGR   latest YoY
+CA      30    30    0.00%  0.00% (2010)
+TX      20    40    7.18%  7.18% (2010)
+
+Ranking 2000 → 2010:
+1.  TX  (was 2.)  ↑1
+2.  CA  (was 1.)  ↓1
+`
+	if b.String() != want {
+		t.Errorf("report without projections:\n%s\nwant\n%s", b.String(), want)
+	}
+}
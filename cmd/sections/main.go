@@ -0,0 +1,85 @@
+// Command sections prints the lessons of a Go file as JSON for other tools.
+//
+// Usage:
+//
+//	sections [-func main] [-indent] file.go...
+//
+// Every comment on a line of its own inside the function starts a section and
+// is its narration; the statements up to the next such comment are its code.
+// With -func "" every top-level function is split. The output is one JSON
+// document with an entry per file and function:
+//
+//	[{"file": "test.go", "function": "main", "sections": [{"title": ..., "narration": ...,
+//	  "code": ..., "code_span": {"start": {"offset", "line", "column"}, "end": ...}, ...}]}]
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/raproid/go-training/lessons"
+)
+
+// function is the JSON shape of one split function.
+type function struct {
+	File     string           `json:"file"`
+	Function string           `json:"function"`
+	Sections []lessons.Lesson `json:"sections"`
+}
+
+func main() {
+	fn := flag.String("func", "main", `function to split into sections; "" splits every function`)
+	indent := flag.Bool("indent", false, "indent the JSON output")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: sections [flags] file.go...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	result := []function{}
+	for _, path := range flag.Args() {
+		fns, err := extract(path, *fn)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "sections:", err)
+			os.Exit(1)
+		}
+		result = append(result, fns...)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	if *indent {
+		enc.SetIndent("", "  ")
+	}
+	if err := enc.Encode(result); err != nil {
+		fmt.Fprintln(os.Stderr, "sections:", err)
+		os.Exit(1)
+	}
+}
+
+func extract(path, fn string) ([]function, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{fn}
+	if fn == "" {
+		if names, err = lessons.FuncNames(path, src); err != nil {
+			return nil, err
+		}
+	}
+	var fns []function
+	for _, name := range names {
+		sections, err := lessons.ParseFile(path, src, name)
+		if err != nil {
+			return nil, err
+		}
+		fns = append(fns, function{File: path, Function: name, Sections: sections})
+	}
+	return fns, nil
+}
@@ -0,0 +1,148 @@
+// Command sitegen exports the tutorial as a static site that needs no Go to host.
+//
+// Usage:
+//
+//	sitegen [-src test.go] [-func main] [-out site] [-run=true] [-timeout 2m]
+//
+// Every lesson gets an HTML and a Markdown page with its narration, the exact
+// source of the section and the output it printed; index.html and index.md
+// hold the table of contents. Pages link to the contents and to the previous
+// and next lesson.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/raproid/go-training/lessons"
+	"github.com/raproid/go-training/lessons/site"
+)
+
+// links lay the pages out as files next to each other.
+var links = site.Links{
+	Index:  "index.html",
+	Lesson: func(slug string) string { return slug + ".html" },
+	Static: func(name string) string { return name },
+}
+
+func main() {
+	src := flag.String("src", "test.go", "Go file holding the tutorial")
+	fn := flag.String("func", "main", "function whose body is split into lessons")
+	out := flag.String("out", "site", "output directory")
+	run := flag.Bool("run", true, "run the tutorial to capture the output of every lesson")
+	timeout := flag.Duration("timeout", 2*time.Minute, "maximum time for building and running the tutorial")
+	flag.Parse()
+
+	if err := export(*src, *fn, *out, *run, *timeout); err != nil {
+		fmt.Fprintln(os.Stderr, "sitegen:", err)
+		os.Exit(1)
+	}
+}
+
+func export(srcPath, fn, out string, run bool, timeout time.Duration) error {
+	src, err := os.ReadFile(srcPath)
+	if err != nil {
+		return err
+	}
+	ls, err := lessons.ParseFile(srcPath, src, fn)
+	if err != nil {
+		return err
+	}
+	if run {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if err := lessons.Capture(ctx, src, ls); err != nil {
+			// as in web.Pages.Capture: a failed lesson still leaves output worth publishing
+			fmt.Fprintln(os.Stderr, "sitegen: running the tutorial:", err)
+		}
+	}
+	if err := os.MkdirAll(out, 0o755); err != nil {
+		return err
+	}
+
+	htmlTemplates, err := site.Parse(site.Files(), links)
+	if err != nil {
+		return err
+	}
+	source := filepath.Base(srcPath)
+	index := site.Index{Source: source, Lessons: ls}
+	if err := render(filepath.Join(out, "index.html"), htmlTemplates.Render, "index", index); err != nil {
+		return err
+	}
+	if err := render(filepath.Join(out, "index.md"), markdownTemplates.ExecuteTemplate, "index", index); err != nil {
+		return err
+	}
+	for i, l := range ls {
+		p := site.NewLesson(ls, i, source)
+		if err := render(filepath.Join(out, l.Slug+".html"), htmlTemplates.Render, "lesson", p); err != nil {
+			return err
+		}
+		if err := render(filepath.Join(out, l.Slug+".md"), markdownTemplates.ExecuteTemplate, "lesson", p); err != nil {
+			return err
+		}
+	}
+	if err := copyStatic(out); err != nil {
+		return err
+	}
+	fmt.Printf("wrote %d lessons to %s\n", len(ls), out)
+	return nil
+}
+
+// render writes a page with the HTML or the Markdown templates.
+func render(path string, execute func(w io.Writer, name string, data any) error, name string, data any) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := execute(f, name, data); err != nil {
+		f.Close()
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// copyStatic writes the stylesheet and the other static files of the pages
+// next to them.
+func copyStatic(out string) error {
+	static, err := fs.Sub(site.Files(), "static")
+	if err != nil {
+		return err
+	}
+	names, err := fs.Glob(static, "*")
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		data, err := fs.ReadFile(static, name)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(out, name), data, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// funcs are the functions of the Markdown templates.
+var funcs = map[string]any{
+	"paragraphs": site.Paragraphs,
+	"fence": func(s string) string {
+		// a fence longer than any run of backticks in the code, so code can't close it
+		n := 3
+		for strings.Contains(s, strings.Repeat("`", n)) {
+			n++
+		}
+		return strings.Repeat("`", n)
+	},
+	"inc": func(i int) int {
+		return i + 1
+	},
+}
@@ -0,0 +1,39 @@
+package main
+
+import "text/template"
+
+var markdownTemplates = template.Must(template.New("").Funcs(funcs).Parse(`
+{{define "index"}}# Go basics
+
+Lessons from ` + "`{{.Source}}`" + `, in the order they run.
+{{range $i, $l := .Lessons}}
+{{inc $i}}. [{{$l.Title}}]({{$l.Slug}}.md) (lines {{$l.StartLine}}–{{$l.EndLine}})
+{{- end}}
+{{end}}
+
+{{define "nav"}}
+{{- if .Prev}}[← {{.Prev.Title}}]({{.Prev.Slug}}.md) · {{end}}[Contents](index.md){{if .Next}} · [{{.Next.Title}} →]({{.Next.Slug}}.md){{end}}
+{{- end}}
+
+{{define "lesson"}}{{template "nav" .}}
+
+# {{.Number}}. {{.Title}}
+{{range paragraphs .Narration}}
+{{.}}
+{{end}}
+{{- if .Code}}
+## Code ({{.Source}}:{{.StartLine}}–{{.EndLine}})
+
+{{fence .Code}}go
+{{.Code}}
+{{fence .Code}}
+{{end}}
+{{- if .Output}}
+## Output
+
+{{fence .Output}}text
+{{.Output}}{{fence .Output}}
+{{end}}
+{{template "nav" .}}
+{{end}}
+`))
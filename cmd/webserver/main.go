@@ -0,0 +1,64 @@
+// Command webserver serves the tutorial over HTTP or HTTPS.
+//
+// Usage:
+//
+//	webserver [-src test.go] [-func main] [-users users.json] [-addr :8080] [-tls-addr :8443]
+//	          [-tls-cert file -tls-key file | -tls-self-signed] [-dev]
+//
+// The lessons of -func in -src are served as HTML pages, with their output
+// captured by running the tutorial once at startup and streamed live on
+// request. The bearer tokens and roles for the protected reports are read
+// from -users; copy users.example.json to get started. Without it the
+// reports answer 401.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+
+	"github.com/raproid/go-training/lessons/site"
+	"github.com/raproid/go-training/web"
+)
+
+func main() {
+	cfg := web.Config{Addr: ":8080", TLSAddr: ":8443"}
+	cfg.Flags(flag.CommandLine)
+	src := flag.String("src", "test.go", "Go file holding the tutorial")
+	fn := flag.String("func", "main", "function whose body is split into lessons")
+	usersFile := flag.String("users", "users.json", "JSON file with the users, their tokens and roles")
+	flag.Parse()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	if err := run(cfg, logger, *src, *fn, *usersFile); err != nil {
+		fmt.Fprintln(os.Stderr, "webserver:", err)
+		os.Exit(1)
+	}
+}
+
+func run(cfg web.Config, logger *slog.Logger, srcPath, fn, usersFile string) error {
+	users, err := web.LoadUsers(usersFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		logger.Warn("no users file, protected routes will answer 401", slog.String("file", usersFile))
+	} else if err != nil {
+		return err
+	}
+	src, err := os.ReadFile(srcPath)
+	if err != nil {
+		return err
+	}
+	files := site.Files()
+	if cfg.Dev {
+		files = os.DirFS(site.Dir)
+	}
+	p, err := web.NewPages(logger, srcPath, src, fn, files, cfg.Dev)
+	if err != nil {
+		return err
+	}
+	go p.Capture(context.Background())
+	return web.Serve(cfg, logger, web.NewHandler(logger, users, p))
+}
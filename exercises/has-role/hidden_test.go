@@ -0,0 +1,47 @@
+//go:build ignore
+
+package exercise
+
+import "testing"
+
+func TestHasRoleSingle(t *testing.T) {
+	var roles byte = IsAdmin | CanSeeFinance | CanSeeEurope
+	if !HasRole(roles, IsAdmin) {
+		t.Error("HasRole(roles, IsAdmin) = false, want true")
+	}
+	if HasRole(roles, IsHeadquarters) {
+		t.Error("HasRole(roles, IsHeadquarters) = true, want false")
+	}
+}
+
+func TestHasRoleCombined(t *testing.T) {
+	var roles byte = IsAdmin | CanSeeEurope
+	if HasRole(roles, IsAdmin|CanSeeFinance) {
+		t.Error("HasRole with a partially matching mask = true, want false")
+	}
+	if !HasRole(roles, IsAdmin|CanSeeEurope) {
+		t.Error("HasRole with a fully matching mask = false, want true")
+	}
+}
+
+func TestHasRoleZero(t *testing.T) {
+	if !HasRole(0xff, 0) || !HasRole(0, 0) {
+		t.Error("HasRole(roles, 0) = false, want true: an empty mask asks for no roles")
+	}
+	if HasRole(0, CanSeeAsia) {
+		t.Error("HasRole(0, CanSeeAsia) = true, want false")
+	}
+}
+
+func TestHasRoleAllContinents(t *testing.T) {
+	continents := []byte{CanSeeAfrica, CanSeeAsia, CanSeeEurope, CanSeeNorthAmerica, CanSeeSouthAmerica}
+	var roles byte
+	for _, c := range continents {
+		roles |= c
+	}
+	for _, c := range continents {
+		if !HasRole(roles, c) {
+			t.Errorf("HasRole(all continents, %08b) = false, want true", c)
+		}
+	}
+}
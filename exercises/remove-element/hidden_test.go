@@ -0,0 +1,52 @@
+//go:build ignore
+
+package exercise
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRemoveMiddle(t *testing.T) {
+	got := Remove([]int{1, 2, 3, 4, 5}, 2)
+	if want := []int{1, 2, 4, 5}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Remove(...) = %v, want %v", got, want)
+	}
+}
+
+func TestRemoveKeepsInput(t *testing.T) {
+	in := []int{1, 2, 3, 4, 5}
+	Remove(in, 2)
+	if want := []int{1, 2, 3, 4, 5}; !reflect.DeepEqual(in, want) {
+		t.Fatalf("input changed to %v, want %v", in, want)
+	}
+}
+
+func TestRemoveFirstAndLast(t *testing.T) {
+	if got, want := Remove([]int{1, 2, 3}, 0), []int{2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Remove(first) = %v, want %v", got, want)
+	}
+	if got, want := Remove([]int{1, 2, 3}, 2), []int{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Remove(last) = %v, want %v", got, want)
+	}
+}
+
+func TestRemoveOutOfRange(t *testing.T) {
+	in := []int{1, 2, 3}
+	got := Remove(in, 7)
+	if want := []int{1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Remove(out of range) = %v, want %v", got, want)
+	}
+	if len(got) > 0 && &got[0] == &in[0] {
+		t.Fatal("Remove(out of range) must return a copy, not the input slice")
+	}
+}
+
+func TestRemoveDoesNotAlias(t *testing.T) {
+	in := []int{1, 2, 3, 4}
+	got := Remove(in, 1)
+	got[0] = 100
+	if in[0] != 1 {
+		t.Fatal("result shares its underlying array with the input")
+	}
+}
@@ -0,0 +1,8 @@
+//go:build ignore
+
+package exercise
+
+// Remove returns a new slice without the element at index i; s must not change.
+func Remove(s []int, i int) []int {
+	return append(s[:i], s[i+1:]...) // the twelfthSlice bug: this writes into s
+}
@@ -0,0 +1,94 @@
+// Package tempmod sets up the temporary modules in which code from outside
+// the repository's own build is compiled: exercise solutions, the tutorial and
+// notebook cells.
+//
+// The modules are built with the local Go toolchain, outside any workspace,
+// and their go.mod asks for the language version of that toolchain, so code
+// gets the features and the per-iteration loop variables of the Go it is
+// built with.
+package tempmod
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/version"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Create makes a temporary directory holding a module with the given path and
+// files, named relative to the directory, and returns the directory; the
+// caller removes it. Go files are written without a //go:build ignore
+// constraint, which keeps them out of the repository's own build.
+func Create(path string, files map[string][]byte) (string, error) {
+	lang, err := goVersion()
+	if err != nil {
+		return "", err
+	}
+	dir, err := os.MkdirTemp("", path+"-")
+	if err != nil {
+		return "", err
+	}
+	all := map[string][]byte{"go.mod": fmt.Appendf(nil, "module %s\n\ngo %s\n", path, lang)}
+	for name, data := range files {
+		if strings.HasSuffix(name, ".go") {
+			data = stripIgnoreTag(data)
+		}
+		all[name] = data
+	}
+	for name, data := range all {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+// Command returns a go command with args, run in dir with the local toolchain.
+func Command(ctx context.Context, dir string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = dir
+	cmd.Env = env()
+	return cmd
+}
+
+func env() []string {
+	return append(os.Environ(), "GOTOOLCHAIN=local", "GOWORK=off", "GOFLAGS=-mod=mod")
+}
+
+// goVersion returns the language version of the local toolchain, like 1.26.
+var goVersion = sync.OnceValues(func() (string, error) {
+	cmd := exec.Command("go", "env", "GOVERSION")
+	cmd.Env = env()
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("go env GOVERSION: %w", err)
+	}
+	lang := version.Lang(strings.TrimSpace(string(out)))
+	if lang == "" {
+		// a development toolchain, which reports something like "devel go1.27-abcdef"
+		lang = version.Lang(runtime.Version())
+	}
+	if lang == "" {
+		return "", fmt.Errorf("unknown Go version %s", bytes.TrimSpace(out))
+	}
+	return strings.TrimPrefix(lang, "go"), nil
+})
+
+// stripIgnoreTag removes the //go:build ignore constraint from src.
+func stripIgnoreTag(src []byte) []byte {
+	var b bytes.Buffer
+	for _, line := range strings.SplitAfter(string(src), "\n") {
+		if strings.TrimSpace(line) == "//go:build ignore" || strings.TrimSpace(line) == "// +build ignore" {
+			continue
+		}
+		b.WriteString(line)
+	}
+	return b.Bytes()
+}
@@ -0,0 +1,65 @@
+package tempmod
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestCreate builds a program that needs Go 1.22: range over an int, and a
+// new loop variable in every iteration.
+func TestCreate(t *testing.T) {
+	src := `//go:build ignore
+
+package main
+
+import "fmt"
+
+func main() {
+	var prints []func()
+	for i := range 3 {
+		prints = append(prints, func() { fmt.Print(i) })
+	}
+	for _, p := range prints {
+		p()
+	}
+}
+`
+	dir, err := Create("loops", map[string][]byte{"main.go": []byte(src), "notes.txt": []byte("//go:build ignore\n")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	mod, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lang, _ := goVersion()
+	if want := "module loops\n\ngo " + lang + "\n"; string(mod) != want {
+		t.Errorf("go.mod is %q, want %q", mod, want)
+	}
+	if notes, _ := os.ReadFile(filepath.Join(dir, "notes.txt")); string(notes) != "//go:build ignore\n" {
+		t.Errorf("a file that isn't Go source was changed to %q", notes)
+	}
+
+	out, err := Command(context.Background(), dir, "run", ".").CombinedOutput()
+	if err != nil {
+		t.Fatalf("go run: %v\n%s", err, out)
+	}
+	if string(out) != "012" {
+		t.Errorf("the program printed %q, want 012", out)
+	}
+}
+
+func TestGoVersion(t *testing.T) {
+	lang, err := goVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.HasPrefix(lang, "go") || strings.Count(lang, ".") != 1 {
+		t.Errorf("goVersion() = %q, want a language version like 1.26", lang)
+	}
+}
@@ -0,0 +1,445 @@
+// Package kvstore is a small embedded key-value store that keeps a map on
+// disk, so data like the statePopulations map of the maps lesson survives a
+// restart:
+//
+//	s, err := kvstore.Open("populations.kv", nil)
+//	...
+//	s.Put("CA", []byte("39250017"))
+//	v, ok := s.Get("CA")
+//
+// The store is a single file: a header followed by an append-only log of
+// records, each a put or a delete protected by a CRC-32 checksum. The latest
+// values are kept in memory, so reads never touch the disk. Overwritten and
+// deleted records stay in the log until it's compacted, which rewrites the
+// live records to a temporary file and renames it over the log, so a crash
+// leaves either the old file or the new one. A record that was only partly
+// written when the process died is cut off the end of the log on the next
+// Open; a damaged record with intact ones after it makes Open fail instead,
+// since cutting it off would lose them.
+//
+// A Store is safe for concurrent use, but only by one process at a time.
+package kvstore
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// magic starts every store file.
+const magic = "kvstore1"
+
+const (
+	opPut byte = iota + 1
+	opDelete
+)
+
+// headerSize is the size of a record header: the CRC-32 of the rest of the
+// record, the operation and the lengths of the key and the value.
+const headerSize = 4 + 1 + 4 + 4
+
+// maxSize limits keys and values; a record header with a longer length is
+// corrupt.
+const maxSize = 1 << 30
+
+var ErrClosed = errors.New("kvstore: store is closed")
+
+// ErrBroken is returned by every write after a failed write couldn't be
+// rolled back, since the file may then hold a record the store doesn't know
+// about. Reopening the store reads the file as it is.
+var ErrBroken = errors.New("kvstore: store is broken")
+
+// Options tune a Store; the zero value is usable.
+type Options struct {
+	// Sync makes every write wait until the record is on disk (fsync).
+	Sync bool
+
+	// CompactAfter is the number of stale records (overwritten or deleted)
+	// after which a write compacts the log, once they also outnumber the live
+	// ones. 0 means 1000; a negative value turns automatic compaction off.
+	CompactAfter int
+
+	// CompactInterval, if positive, compacts the log in the background at
+	// this interval whenever it has stale records.
+	CompactInterval time.Duration
+}
+
+// Store is an open store file.
+type Store struct {
+	path string
+	opts Options
+
+	mu     sync.RWMutex
+	f      *os.File
+	size   int64 // bytes of the log known to be good
+	data   map[string][]byte
+	stale  int // records in the log that no longer matter
+	closed bool
+	broken error // why the file no longer matches size and data, see ErrBroken
+
+	stop     chan struct{} // closed by Close to end the compaction goroutine
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// Open opens the store at path, creating it if it doesn't exist, and reads
+// the log into memory. A damaged record at the end of the log, left by a
+// crash in the middle of a write, is dropped; a damaged record followed by
+// intact ones is an error, and the file is left as it is.
+func Open(path string, opts *Options) (*Store, error) {
+	s := &Store{path: path, data: map[string][]byte{}}
+	if opts != nil {
+		s.opts = *opts
+	}
+	if s.opts.CompactAfter == 0 {
+		s.opts.CompactAfter = 1000
+	}
+
+	// temporary files of a compaction that crashed before the rename
+	if leftovers, err := filepath.Glob(path + ".*.tmp"); err == nil {
+		for _, name := range leftovers {
+			os.Remove(name)
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.load(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	s.f = f
+
+	if s.opts.CompactInterval > 0 {
+		s.stop, s.done = make(chan struct{}), make(chan struct{})
+		go s.compactLoop()
+	}
+	return s, nil
+}
+
+// load replays the log in f. A damaged record at the end, with nothing intact
+// after it, is what a crash in the middle of an append leaves behind, and is
+// cut off; damage anywhere else is reported and the file is left alone.
+func (s *Store) load(f *os.File) error {
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return err // a read error says nothing about the file, so don't touch it
+	}
+	if len(data) == 0 {
+		if _, err := f.Write([]byte(magic)); err != nil {
+			return err
+		}
+		s.size = int64(len(magic))
+		return f.Sync()
+	}
+	if !bytes.HasPrefix(data, []byte(magic)) {
+		return fmt.Errorf("kvstore: %s is not a store file", s.path)
+	}
+
+	off := len(magic)
+	for off < len(data) {
+		op, key, value, n, err := decodeRecord(data[off:])
+		if err != nil {
+			if next, ok := findRecord(data[off+1:]); ok {
+				return fmt.Errorf("kvstore: %s: %v at offset %d, with intact records after it at offset %d", s.path, err, off, off+1+next)
+			}
+			if err := f.Truncate(int64(off)); err != nil {
+				return err
+			}
+			break
+		}
+		s.apply(op, key, bytes.Clone(value))
+		off += n
+	}
+	s.size = int64(off)
+	_, err = f.Seek(s.size, io.SeekStart)
+	return err
+}
+
+var (
+	errTorn    = errors.New("record cut short")
+	errCorrupt = errors.New("corrupt record")
+)
+
+// decodeRecord decodes the record at the start of b and returns its operation,
+// key and value and its size in bytes. The value points into b.
+func decodeRecord(b []byte) (op byte, key string, value []byte, n int, err error) {
+	if len(b) < headerSize {
+		return 0, "", nil, 0, errTorn
+	}
+	op = b[4]
+	klen, vlen := binary.LittleEndian.Uint32(b[5:]), binary.LittleEndian.Uint32(b[9:])
+	if op != opPut && op != opDelete || klen > maxSize || vlen > maxSize {
+		return 0, "", nil, 0, errCorrupt
+	}
+	n = headerSize + int(klen) + int(vlen)
+	if len(b) < n {
+		return 0, "", nil, 0, errTorn
+	}
+	if crc32.ChecksumIEEE(b[4:n]) != binary.LittleEndian.Uint32(b) {
+		return 0, "", nil, 0, fmt.Errorf("%w: checksum mismatch", errCorrupt)
+	}
+	return op, string(b[headerSize : headerSize+klen]), b[headerSize+klen : n], n, nil
+}
+
+// findRecord returns the offset of the first intact record in b, trying every
+// byte; a damaged record's lengths can't be trusted to find the next one.
+func findRecord(b []byte) (int, bool) {
+	for i := 0; i+headerSize <= len(b); i++ {
+		if _, _, _, _, err := decodeRecord(b[i:]); err == nil {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// appendRecord appends the encoding of a record to buf.
+func appendRecord(buf []byte, op byte, key string, value []byte) []byte {
+	start := len(buf)
+	buf = append(buf, 0, 0, 0, 0, op)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(key)))
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(value)))
+	buf = append(buf, key...)
+	buf = append(buf, value...)
+	binary.LittleEndian.PutUint32(buf[start:], crc32.ChecksumIEEE(buf[start+4:]))
+	return buf
+}
+
+// apply updates the in-memory map with a record and counts the records it
+// makes stale.
+func (s *Store) apply(op byte, key string, value []byte) {
+	_, existed := s.data[key]
+	if existed {
+		s.stale++ // the previous record of the key
+	}
+	switch op {
+	case opPut:
+		s.data[key] = value
+	case opDelete:
+		delete(s.data, key)
+		s.stale++ // the delete itself is only needed until the next compaction
+	}
+}
+
+// Get returns a copy of the value of key, and whether the key exists.
+func (s *Store) Get(key string) ([]byte, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.data[key]
+	return bytes.Clone(v), ok
+}
+
+// Put sets the value of key.
+func (s *Store) Put(key string, value []byte) error {
+	if len(key) > maxSize || len(value) > maxSize {
+		return errors.New("kvstore: keys and values are limited to 1GB")
+	}
+	return s.write(opPut, key, bytes.Clone(value))
+}
+
+// Delete removes key; deleting a key that doesn't exist does nothing.
+func (s *Store) Delete(key string) error {
+	s.mu.RLock()
+	_, ok := s.data[key]
+	s.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+	return s.write(opDelete, key, nil)
+}
+
+func (s *Store) write(op byte, key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+	if s.broken != nil {
+		return s.broken
+	}
+	if op == opDelete {
+		if _, ok := s.data[key]; !ok {
+			return nil // deleted concurrently
+		}
+	}
+
+	rec := appendRecord(nil, op, key, value)
+	_, err := s.f.Write(rec)
+	if err == nil && s.opts.Sync {
+		err = s.f.Sync()
+	}
+	if err != nil {
+		s.rollback()
+		return err
+	}
+	s.size += int64(len(rec))
+	s.apply(op, key, value)
+
+	if s.opts.CompactAfter > 0 && s.stale >= s.opts.CompactAfter && s.stale > len(s.data) {
+		return s.compact()
+	}
+	return nil
+}
+
+// rollback cuts off whatever part of a failed write made it into the file,
+// so that it doesn't come back on the next Open and later records don't end
+// up behind a torn one. If that fails too, the store is marked broken.
+func (s *Store) rollback() {
+	err := s.f.Truncate(s.size)
+	if err == nil {
+		_, err = s.f.Seek(s.size, io.SeekStart)
+	}
+	if err != nil {
+		s.broken = fmt.Errorf("%w: rolling back a failed write to %s: %v", ErrBroken, s.path, err)
+	}
+}
+
+// Range calls fn for every key and value in the order of the keys, until fn
+// returns false. The values must not be modified. The store may be changed
+// from fn; Range works on the keys as they were when it started.
+func (s *Store) Range(fn func(key string, value []byte) bool) {
+	s.mu.RLock()
+	keys := slices.Sorted(maps.Keys(s.data))
+	s.mu.RUnlock()
+	for _, k := range keys {
+		s.mu.RLock()
+		v, ok := s.data[k]
+		s.mu.RUnlock()
+		if ok && !fn(k, v) {
+			return
+		}
+	}
+}
+
+// Len returns the number of keys.
+func (s *Store) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.data)
+}
+
+// Compact rewrites the log with only the current value of every key.
+func (s *Store) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+	if s.broken != nil {
+		return s.broken
+	}
+	return s.compact()
+}
+
+// compact writes the live records to a temporary file next to the log and
+// renames it over the log; it must be called with s.mu held.
+func (s *Store) compact() error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // fails harmlessly once the rename is done
+
+	w := bufio.NewWriter(tmp)
+	w.WriteString(magic)
+	size := int64(len(magic))
+	var buf []byte
+	for _, k := range slices.Sorted(maps.Keys(s.data)) {
+		buf = appendRecord(buf[:0], opPut, k, s.data[k])
+		w.Write(buf)
+		size += int64(len(buf))
+	}
+	err = w.Flush()
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err == nil {
+		err = tmp.Chmod(0o644)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return err
+	}
+	syncDir(filepath.Dir(s.path))
+
+	// the old file is gone from the directory; continue on the new one
+	f, err := os.OpenFile(s.path, os.O_RDWR, 0)
+	if err != nil {
+		s.closed = true // nowhere to write to anymore
+		s.f.Close()
+		return fmt.Errorf("kvstore: reopening after compaction: %w", err)
+	}
+	if _, err := f.Seek(size, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+	s.f.Close()
+	s.f, s.size, s.stale = f, size, 0
+	return nil
+}
+
+// syncDir makes a rename in dir durable. It's best effort: some systems
+// can't open or sync directories.
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
+
+func (s *Store) compactLoop() {
+	defer close(s.done)
+	t := time.NewTicker(s.opts.CompactInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-t.C:
+			s.mu.Lock()
+			if !s.closed && s.broken == nil && s.stale > 0 {
+				s.compact() // a failed compaction leaves the log as it was; the next tick tries again
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// Close stops the background compaction and closes the file. The store
+// can't be used afterwards.
+func (s *Store) Close() error {
+	if s.stop != nil {
+		s.stopOnce.Do(func() {
+			close(s.stop)
+			<-s.done
+		})
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+	s.closed = true
+	if err := s.f.Sync(); err != nil {
+		s.f.Close()
+		return err
+	}
+	return s.f.Close()
+}
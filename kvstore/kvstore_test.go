@@ -0,0 +1,363 @@
+package kvstore
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func open(t *testing.T, path string, opts *Options) *Store {
+	t.Helper()
+	s, err := Open(path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func put(t *testing.T, s *Store, key, value string) {
+	t.Helper()
+	if err := s.Put(key, []byte(value)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// check compares the contents of s with want.
+func check(t *testing.T, s *Store, want map[string]string) {
+	t.Helper()
+	if s.Len() != len(want) {
+		t.Errorf("Len() = %d, want %d", s.Len(), len(want))
+	}
+	for k, v := range want {
+		if got, ok := s.Get(k); !ok || string(got) != v {
+			t.Errorf("Get(%q) = %q, %v, want %q", k, got, ok, v)
+		}
+	}
+}
+
+func fileSize(t *testing.T, path string) int64 {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info.Size()
+}
+
+func TestPutGetDelete(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "populations.kv")
+	s := open(t, path, nil)
+	put(t, s, "CA", "39250017")
+	put(t, s, "TX", "27862596")
+	put(t, s, "CA", "39536653")
+	if err := s.Delete("TX"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete("NY"); err != nil {
+		t.Errorf("deleting a missing key: %v", err)
+	}
+	check(t, s, map[string]string{"CA": "39536653"})
+	if _, ok := s.Get("TX"); ok {
+		t.Error("TX is still there after Delete")
+	}
+
+	v, _ := s.Get("CA")
+	v[0] = 'x'
+	if v, _ := s.Get("CA"); string(v) != "39536653" {
+		t.Errorf("changing the result of Get changed the store: %q", v)
+	}
+}
+
+func TestReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "populations.kv")
+	s, err := Open(path, &Options{Sync: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	put(t, s, "CA", "39250017")
+	put(t, s, "TX", "27862596")
+	put(t, s, "FL", "20612439")
+	put(t, s, "CA", "39536653")
+	if err := s.Delete("FL"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put("NY", nil); !errors.Is(err, ErrClosed) {
+		t.Errorf("Put after Close: %v, want ErrClosed", err)
+	}
+	if err := s.Close(); !errors.Is(err, ErrClosed) {
+		t.Errorf("second Close: %v, want ErrClosed", err)
+	}
+
+	s = open(t, path, nil)
+	check(t, s, map[string]string{"CA": "39536653", "TX": "27862596"})
+	if s.stale != 3 {
+		t.Errorf("stale = %d after reopening, want 3", s.stale)
+	}
+	put(t, s, "NY", "19745289")
+	s.Close()
+	check(t, open(t, path, nil), map[string]string{"CA": "39536653", "TX": "27862596", "NY": "19745289"})
+}
+
+func TestRange(t *testing.T) {
+	s := open(t, filepath.Join(t.TempDir(), "populations.kv"), nil)
+	for _, k := range []string{"TX", "CA", "FL"} {
+		put(t, s, k, k)
+	}
+	var keys []string
+	s.Range(func(key string, value []byte) bool {
+		keys = append(keys, key)
+		s.Delete("FL") // changing the store from fn is allowed, and FL is skipped
+		return len(keys) < 2
+	})
+	if fmt.Sprint(keys) != "[CA TX]" {
+		t.Errorf("Range visited %v, want [CA TX]", keys)
+	}
+}
+
+func TestNotAStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, []byte("some notes"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path, nil); err == nil {
+		t.Error("opening a file that isn't a store: no error")
+	}
+}
+
+// writeLog writes a store file holding records put as keys k0, k1, ... with
+// values v0, v1, ..., and returns it and the offset of every record.
+func writeLog(t *testing.T, n int) (path string, offsets []int) {
+	t.Helper()
+	buf := []byte(magic)
+	for i := range n {
+		offsets = append(offsets, len(buf))
+		buf = appendRecord(buf, opPut, fmt.Sprint("k", i), fmt.Append(nil, "v", i))
+	}
+	path = filepath.Join(t.TempDir(), "log.kv")
+	if err := os.WriteFile(path, buf, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path, offsets
+}
+
+func firstRecords(n int) map[string]string {
+	m := map[string]string{}
+	for i := range n {
+		m[fmt.Sprint("k", i)] = fmt.Sprint("v", i)
+	}
+	return m
+}
+
+// TestTornTail checks that a record damaged by a crash in the middle of an
+// append is cut off the end of the log, and that the store goes on from there.
+func TestTornTail(t *testing.T) {
+	tests := []struct {
+		name   string
+		damage func(log []byte, last int) []byte
+	}{
+		{"partial header", func(log []byte, last int) []byte { return log[:last+5] }},
+		{"partial key", func(log []byte, last int) []byte { return log[:last+headerSize+1] }},
+		{"partial value", func(log []byte, last int) []byte { return log[:len(log)-1] }},
+		{"bad checksum", func(log []byte, last int) []byte {
+			log[len(log)-1] ^= 0xff
+			return log
+		}},
+		{"zeroed", func(log []byte, last int) []byte {
+			clear(log[last:])
+			return append(log, make([]byte, 100)...)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, offsets := writeLog(t, 4)
+			last := offsets[3]
+			log, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, tt.damage(log, last), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			s, err := Open(path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			check(t, s, firstRecords(3))
+			if size := fileSize(t, path); size != int64(last) {
+				t.Errorf("file size %d after Open, want %d", size, last)
+			}
+			put(t, s, "k3", "again")
+			s.Close()
+
+			s = open(t, path, nil)
+			want := firstRecords(3)
+			want["k3"] = "again"
+			check(t, s, want)
+		})
+	}
+}
+
+// TestCorruption checks that a damaged record with intact ones after it makes
+// Open fail without changing the file.
+func TestCorruption(t *testing.T) {
+	tests := []struct {
+		name string
+		at   func(offsets []int) int // the byte to flip
+	}{
+		{"checksum", func(offsets []int) int { return offsets[0] }},
+		{"operation", func(offsets []int) int { return offsets[1] + 4 }},
+		{"key length", func(offsets []int) int { return offsets[0] + 5 }},
+		{"value length", func(offsets []int) int { return offsets[1] + 9 }},
+		{"value", func(offsets []int) int { return offsets[1] - 1 }},
+		{"next to last record", func(offsets []int) int { return offsets[3] - 1 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, offsets := writeLog(t, 4)
+			log, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			log[tt.at(offsets)] ^= 0x40
+			if err := os.WriteFile(path, log, 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			if s, err := Open(path, nil); err == nil {
+				s.Close()
+				t.Fatal("Open succeeded on a log with a damaged record in the middle")
+			}
+			after, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(after, log) {
+				t.Errorf("the failed Open changed the file from %d to %d bytes", len(log), len(after))
+			}
+		})
+	}
+}
+
+// TestBroken checks that a failed write that can't be rolled back makes later
+// writes fail, and that the records written before it are kept.
+func TestBroken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "populations.kv")
+	s := open(t, path, &Options{Sync: true})
+	put(t, s, "CA", "39250017")
+	size := fileSize(t, path)
+
+	s.f.Close() // every write, sync and truncate fails from now on
+	if err := s.Put("TX", []byte("27862596")); err == nil || errors.Is(err, ErrBroken) {
+		t.Fatalf("the failed write: %v, want the error of the write itself", err)
+	}
+	for name, err := range map[string]error{
+		"Put":     s.Put("FL", []byte("20612439")),
+		"Delete":  s.Delete("CA"),
+		"Compact": s.Compact(),
+	} {
+		if !errors.Is(err, ErrBroken) {
+			t.Errorf("%s after the failed rollback: %v, want ErrBroken", name, err)
+		}
+	}
+	check(t, s, map[string]string{"CA": "39250017"})
+
+	if got := fileSize(t, path); got != size {
+		t.Errorf("file size %d, want %d", got, size)
+	}
+	check(t, open(t, path, nil), map[string]string{"CA": "39250017"})
+}
+
+func TestCompact(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "populations.kv")
+	leftover := path + ".123.tmp"
+	if err := os.WriteFile(leftover, []byte("half a compaction"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := open(t, path, &Options{CompactAfter: -1})
+	if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+		t.Errorf("the leftover temporary file is still there: %v", err)
+	}
+
+	want := map[string]string{}
+	for i := range 100 {
+		k, v := fmt.Sprint("k", i%10), fmt.Sprint("v", i)
+		put(t, s, k, v)
+		want[k] = v
+	}
+	if err := s.Delete("k0"); err != nil {
+		t.Fatal(err)
+	}
+	delete(want, "k0")
+	before := fileSize(t, path)
+
+	if err := s.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if size := fileSize(t, path); size >= before || size != s.size {
+		t.Errorf("file size %d after compaction, was %d; the store thinks %d", size, before, s.size)
+	}
+	if s.stale != 0 {
+		t.Errorf("stale = %d after compaction", s.stale)
+	}
+	check(t, s, want)
+
+	// the store writes to the new file, not the renamed-over one
+	put(t, s, "k1", "after")
+	want["k1"] = "after"
+	s.Close()
+	check(t, open(t, path, nil), want)
+	if matches, _ := filepath.Glob(filepath.Join(dir, "*.tmp")); len(matches) != 0 {
+		t.Errorf("temporary files left: %v", matches)
+	}
+}
+
+func TestCompactAfter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "populations.kv")
+	s := open(t, path, &Options{CompactAfter: 5})
+	put(t, s, "CA", "0")
+	for i := range 5 {
+		put(t, s, "CA", fmt.Sprint(i+1))
+	}
+	if s.stale != 0 {
+		t.Errorf("stale = %d, want a compaction at 5", s.stale)
+	}
+	if size := fileSize(t, path); size != int64(len(appendRecord([]byte(magic), opPut, "CA", []byte("5")))) {
+		t.Errorf("file size %d after the automatic compaction, want a single record", size)
+	}
+	check(t, s, map[string]string{"CA": "5"})
+}
+
+func TestCompactInterval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "populations.kv")
+	s := open(t, path, &Options{CompactAfter: -1, CompactInterval: 10 * time.Millisecond})
+	put(t, s, "CA", "1")
+	put(t, s, "CA", "2")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s.mu.RLock()
+		stale := s.stale
+		s.mu.RUnlock()
+		if stale == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("no background compaction")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	check(t, open(t, path, nil), map[string]string{"CA": "2"})
+}
@@ -0,0 +1,229 @@
+// Package lessons splits a tutorial file like test.go into lessons and runs them.
+//
+// A lesson starts at a comment on a line of its own inside the tutorial
+// function and runs up to the next such comment. The comment is the lesson's
+// narration and the statements that follow are its code. Comments at the end of
+// a line belong to the code and don't start a new lesson.
+package lessons
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Lesson is one section of the tutorial function.
+type Lesson struct {
+	Slug          string `json:"slug"` // unique, URL-friendly name derived from the title
+	Title         string `json:"title"`
+	Narration     string `json:"narration"` // comment text without the comment markers
+	NarrationSpan *Span  `json:"narration_span,omitempty"`
+	Code          string `json:"code"` // exact source of the statements, dedented
+	CodeSpan      *Span  `json:"code_span,omitempty"`
+	StartLine     int    `json:"start_line"`       // first line of the narration
+	EndLine       int    `json:"end_line"`         // last line of the code, or of the narration if there's no code
+	Output        string `json:"output,omitempty"` // filled in by Capture
+
+	stmtOffset int // byte offset of the first statement, -1 if there is none
+}
+
+// Span is a range of source text; End points just past its last character.
+type Span struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Position is a place in the source; lines and columns start at 1, columns count bytes.
+type Position struct {
+	Offset int `json:"offset"`
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+func position(p token.Position) Position {
+	return Position{Offset: p.Offset, Line: p.Line, Column: p.Column}
+}
+
+// ParseFile parses the Go source src and splits the body of the function
+// funcName into lessons. The filename is only used in error messages.
+func ParseFile(filename string, src []byte, funcName string) ([]Lesson, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	var body *ast.BlockStmt
+	for _, decl := range f.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && fn.Name.Name == funcName && fn.Body != nil {
+			body = fn.Body
+		}
+	}
+	if body == nil {
+		return nil, fmt.Errorf("%s: no function %s", filename, funcName)
+	}
+	file := fset.File(f.Pos())
+	lines := bytes.SplitAfter(src, []byte("\n"))
+
+	// collect the comments that sit between top-level statements on lines of their own
+	var groups []*ast.CommentGroup
+	for _, cg := range f.Comments {
+		if cg.Pos() <= body.Lbrace || cg.End() >= body.Rbrace || insideStmt(body.List, cg) {
+			continue
+		}
+		pos, end := fset.Position(cg.Pos()), fset.Position(cg.End())
+		if len(bytes.TrimSpace(lines[pos.Line-1][:pos.Column-1])) > 0 {
+			continue // trailing comment
+		}
+		if len(bytes.TrimSpace(lines[end.Line-1][end.Column-1:])) > 0 {
+			continue // comment in front of code, like /* note */ x := 1
+		}
+		groups = append(groups, cg)
+	}
+
+	var lessons []Lesson
+	g, s := 0, 0
+	for g < len(groups) || s < len(body.List) {
+		l := Lesson{stmtOffset: -1}
+		var narration []string
+		// consecutive comments without code between them make up one narration
+		for g < len(groups) && (s == len(body.List) || groups[g].Pos() < body.List[s].Pos()) {
+			if len(narration) == 0 {
+				l.StartLine = fset.Position(groups[g].Pos()).Line
+				l.NarrationSpan = &Span{Start: position(fset.Position(groups[g].Pos()))}
+			}
+			narration = append(narration, strings.TrimSpace(groups[g].Text()))
+			l.EndLine = fset.Position(groups[g].End()).Line
+			l.NarrationSpan.End = position(fset.Position(groups[g].End()))
+			g++
+		}
+		l.Narration = strings.Join(narration, "\n\n")
+
+		first := s
+		for s < len(body.List) && (g == len(groups) || body.List[s].Pos() < groups[g].Pos()) {
+			s++
+		}
+		if first < s {
+			l.stmtOffset = file.Offset(body.List[first].Pos())
+			startLine := fset.Position(body.List[first].Pos()).Line
+			if l.StartLine == 0 {
+				l.StartLine = startLine
+			}
+			// the code runs up to the next narration or the closing brace, so trailing comments are kept
+			endLine := fset.Position(body.Rbrace).Line - 1
+			if g < len(groups) {
+				endLine = fset.Position(groups[g].Pos()).Line - 1
+			}
+			for endLine > startLine && len(bytes.TrimSpace(lines[endLine-1])) == 0 {
+				endLine--
+			}
+			l.EndLine = endLine
+			l.Code = dedent(lines[startLine-1 : endLine])
+			end := file.LineStart(endLine) + token.Pos(len(bytes.TrimRight(lines[endLine-1], " \t\r\n")))
+			l.CodeSpan = &Span{
+				Start: position(fset.Position(body.List[first].Pos())),
+				End:   position(fset.Position(end)),
+			}
+		}
+		l.Title = title(l.Narration, len(lessons)+1)
+		lessons = append(lessons, l)
+	}
+
+	// repeated titles get -2, -3, ..., skipping slugs that are taken, as maps-2 is by a lesson titled "maps 2"
+	taken := map[string]bool{}
+	for i := range lessons {
+		base := slugify(lessons[i].Title)
+		slug := base
+		for n := 2; taken[slug]; n++ {
+			slug = base + "-" + strconv.Itoa(n)
+		}
+		taken[slug] = true
+		lessons[i].Slug = slug
+	}
+	return lessons, nil
+}
+
+// insideStmt reports whether cg lies within one of the statements, e.g. in the body of an if.
+func insideStmt(stmts []ast.Stmt, cg *ast.CommentGroup) bool {
+	for _, s := range stmts {
+		if s.Pos() < cg.Pos() && cg.End() < s.End() {
+			return true
+		}
+	}
+	return false
+}
+
+// title returns the first phrase of the narration, e.g. "slices" for
+// "slices: they are a reference type; ...".
+func title(narration string, n int) string {
+	line, _, _ := strings.Cut(narration, "\n")
+	if i := strings.IndexAny(line, ":;("); i > 0 {
+		line = line[:i]
+	}
+	if i := strings.Index(line, ". "); i > 0 {
+		line = line[:i]
+	}
+	line = strings.TrimSpace(strings.TrimRight(line, ". "))
+	if line == "" {
+		return "Lesson " + strconv.Itoa(n)
+	}
+	return line
+}
+
+// slugify turns a title into a lowercase, dash-separated name of at most six words.
+func slugify(title string) string {
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) > 6 {
+		words = words[:6]
+	}
+	if len(words) == 0 {
+		return "lesson"
+	}
+	return strings.Join(words, "-")
+}
+
+// dedent joins the lines and removes the indentation they all share.
+func dedent(lines [][]byte) string {
+	prefix := ""
+	first := true
+	for _, l := range lines {
+		if len(bytes.TrimSpace(l)) == 0 {
+			continue
+		}
+		indent := string(l[:len(l)-len(bytes.TrimLeft(l, " \t"))])
+		if first {
+			prefix, first = indent, false
+			continue
+		}
+		for !strings.HasPrefix(indent, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	var b strings.Builder
+	for _, l := range lines {
+		b.Write(bytes.TrimPrefix(l, []byte(prefix)))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// FuncNames returns the names of the top-level functions in src that have a
+// body, in source order; any of them can be passed to ParseFile.
+func FuncNames(filename string, src []byte) ([]string, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), filename, src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, decl := range f.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && fn.Body != nil {
+			names = append(names, fn.Name.Name)
+		}
+	}
+	return names, nil
+}
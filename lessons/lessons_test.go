@@ -0,0 +1,200 @@
+package lessons
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+const tutorial = `package main
+
+import "fmt"
+
+func helper() {
+	// not part of the tutorial
+}
+
+func main() {
+	// slices: they are a reference type; they share memory
+	s := []int{1, 2, 3}
+	t := s // t and s share the array
+	t[0] = 100
+	fmt.Println(s)
+
+	// maps
+	// keys are unordered
+	m := map[string]int{"CA": 1}
+	if len(m) > 0 {
+		// inside the if, so part of the code
+		fmt.Println(m)
+	}
+
+	// maps. Deleting keys
+	delete(m, "CA")
+	/* a block comment */ fmt.Println(m)
+
+	// maps
+	fmt.Println(len(m))
+
+	// Maps 2
+	fmt.Println("done")
+	// trailing narration
+}
+`
+
+func parse(t *testing.T, src string) []Lesson {
+	t.Helper()
+	lessons, err := ParseFile("test.go", []byte(src), "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lessons
+}
+
+func TestParseFile(t *testing.T) {
+	lessons := parse(t, tutorial)
+	want := []struct {
+		slug, title, narration, code string
+		start, end                   int
+	}{
+		{"slices", "slices", "slices: they are a reference type; they share memory",
+			"s := []int{1, 2, 3}\nt := s // t and s share the array\nt[0] = 100\nfmt.Println(s)", 10, 14},
+		{"maps", "maps", "maps\nkeys are unordered",
+			"m := map[string]int{\"CA\": 1}\nif len(m) > 0 {\n\t// inside the if, so part of the code\n\tfmt.Println(m)\n}", 16, 22},
+		{"maps-2", "maps", "maps. Deleting keys", "delete(m, \"CA\")\n/* a block comment */ fmt.Println(m)", 24, 26},
+		{"maps-3", "maps", "maps", "fmt.Println(len(m))", 28, 29},
+		{"maps-2-2", "Maps 2", "Maps 2", "fmt.Println(\"done\")", 31, 32},
+		{"trailing-narration", "trailing narration", "trailing narration", "", 33, 33},
+	}
+	if len(lessons) != len(want) {
+		for _, l := range lessons {
+			t.Logf("%s: %q", l.Slug, l.Code)
+		}
+		t.Fatalf("%d lessons, want %d", len(lessons), len(want))
+	}
+	for i, w := range want {
+		l := lessons[i]
+		if l.Slug != w.slug || l.Title != w.title || l.Narration != w.narration || l.Code != w.code ||
+			l.StartLine != w.start || l.EndLine != w.end {
+			t.Errorf("lesson %d = %q %q %q\n%s\nlines %d-%d, want %q %q %q\n%s\nlines %d-%d",
+				i+1, l.Slug, l.Title, l.Narration, l.Code, l.StartLine, l.EndLine,
+				w.slug, w.title, w.narration, w.code, w.start, w.end)
+		}
+	}
+
+	var slugs []string
+	for _, l := range lessons {
+		slugs = append(slugs, l.Slug)
+	}
+	slices.Sort(slugs)
+	if len(slices.Compact(slugs)) != len(lessons) {
+		t.Errorf("duplicate slugs: %v", slugs)
+	}
+}
+
+// TestSpans checks that the spans point at the narration and the code in the
+// source, so that editors can jump to them.
+func TestSpans(t *testing.T) {
+	lessons := parse(t, tutorial)
+	text := func(s *Span) string { return tutorial[s.Start.Offset:s.End.Offset] }
+
+	slices := lessons[0]
+	if got := text(slices.NarrationSpan); got != "// slices: they are a reference type; they share memory" {
+		t.Errorf("narration span %q", got)
+	}
+	if s := slices.NarrationSpan.Start; s.Line != 10 || s.Column != 2 {
+		t.Errorf("narration starts at %d:%d, want 10:2", s.Line, s.Column)
+	}
+	if got := text(slices.CodeSpan); !strings.HasPrefix(got, "s := []int{1, 2, 3}\n\tt := s // t and s share") || !strings.HasSuffix(got, "fmt.Println(s)") {
+		t.Errorf("code span %q", got)
+	}
+	if e := slices.CodeSpan.End; e.Line != 14 || e.Column != 16 {
+		t.Errorf("code ends at %d:%d, want 14:16", e.Line, e.Column)
+	}
+
+	maps := lessons[1]
+	if got := text(maps.NarrationSpan); got != "// maps\n\t// keys are unordered" {
+		t.Errorf("narration span of two comments %q", got)
+	}
+	if got := text(maps.CodeSpan); !strings.HasSuffix(got, "fmt.Println(m)\n\t}") {
+		t.Errorf("code span %q doesn't end at the closing brace of the if", got)
+	}
+
+	if l := lessons[5]; l.CodeSpan != nil || l.stmtOffset != -1 {
+		t.Errorf("a lesson without code has the code span %v", l.CodeSpan)
+	}
+}
+
+func TestParseFileErrors(t *testing.T) {
+	if _, err := ParseFile("test.go", []byte(tutorial), "tutorial"); err == nil || err.Error() != "test.go: no function tutorial" {
+		t.Errorf("missing function: %v", err)
+	}
+	if _, err := ParseFile("test.go", []byte("package main\nfunc main() {"), "main"); err == nil {
+		t.Error("syntax error: no error")
+	}
+	if lessons := parse(t, "package main\n\nfunc main() {}\n"); len(lessons) != 0 {
+		t.Errorf("an empty function has lessons %v", lessons)
+	}
+	lessons := parse(t, "package main\n\nfunc main() {\n\tprintln(1)\n}\n")
+	if len(lessons) != 1 || lessons[0].Title != "Lesson 1" || lessons[0].Slug != "lesson-1" || lessons[0].StartLine != 4 {
+		t.Errorf("code without narration: %+v", lessons)
+	}
+}
+
+func TestTitle(t *testing.T) {
+	tests := []struct {
+		narration, want string
+	}{
+		{"slices: they are a reference type", "slices"},
+		{"maps; unordered", "maps"},
+		{"defer (runs at the end)", "defer"},
+		{"First sentence. Second one.", "First sentence"},
+		{"v1.2 is a version.", "v1.2 is a version"},
+		{"first line\nsecond line", "first line"},
+		{": nothing before the colon", ": nothing before the colon"},
+		{"", "Lesson 3"},
+	}
+	for _, tt := range tests {
+		if got := title(tt.narration, 3); got != tt.want {
+			t.Errorf("title(%q) = %q, want %q", tt.narration, got, tt.want)
+		}
+	}
+}
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		title, want string
+	}{
+		{"slices", "slices"},
+		{"AND, OR & shifts", "and-or-shifts"},
+		{"one two three four five six seven", "one-two-three-four-five-six"},
+		{"Ünïcode façade", "ünïcode-façade"},
+		{"---", "lesson"},
+	}
+	for _, tt := range tests {
+		if got := slugify(tt.title); got != tt.want {
+			t.Errorf("slugify(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestDedent(t *testing.T) {
+	tests := []struct {
+		lines []string
+		want  string
+	}{
+		{[]string{"\t\ta\n", "\t\t\tb\n", "\t\tc\n"}, "a\n\tb\nc"},
+		{[]string{"\t\ta\n", "\n", "\tb\n"}, "\ta\n\nb"},
+		{[]string{"    a\n", "  \tb\n"}, "  a\n\tb"},
+		{[]string{"a\n"}, "a"},
+	}
+	for _, tt := range tests {
+		var lines [][]byte
+		for _, l := range tt.lines {
+			lines = append(lines, []byte(l))
+		}
+		if got := dedent(lines); got != tt.want {
+			t.Errorf("dedent(%q) = %q, want %q", tt.lines, got, tt.want)
+		}
+	}
+}
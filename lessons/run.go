@@ -0,0 +1,116 @@
+package lessons
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/raproid/go-training/internal/tempmod"
+)
+
+// marker starts a line that the instrumented program prints when a lesson
+// begins; it's a control character so it can't clash with real output.
+const marker = "\x1elesson "
+
+// markerFile is added next to the instrumented source; it imports os itself so
+// that the tutorial's own imports don't have to change.
+const markerFile = `package main
+
+import (
+	"os"
+	"strconv"
+)
+
+func lessonMarker(n int) {
+	os.Stdout.WriteString("\x1elesson " + strconv.Itoa(n) + "\n")
+}
+`
+
+// Run compiles src, a main package, with the local Go toolchain and calls emit
+// for every line of output it prints, together with the index of the lesson
+// that printed it. Output of deferred calls ends up in the last lesson that
+// ran. The program is killed when ctx is done.
+func Run(ctx context.Context, src []byte, lessons []Lesson, emit func(lesson int, line string)) error {
+	dir, err := tempmod.Create("lessons", map[string][]byte{
+		"main.go":      instrument(src, lessons),
+		"zz_marker.go": []byte(markerFile),
+	})
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	build := tempmod.Command(ctx, dir, "build", "-o", "lesson", ".")
+	if out, err := build.CombinedOutput(); err != nil {
+		return fmt.Errorf("build: %v\n%s", err, out)
+	}
+
+	cmd := exec.CommandContext(ctx, filepath.Join(dir, "lesson"))
+	cmd.Dir = dir
+	pr, pw := io.Pipe()
+	cmd.Stdout = pw // the same writer for both, so exec uses a single pipe and keeps the order
+	cmd.Stderr = pw
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		current := 0
+		sc := bufio.NewScanner(pr)
+		sc.Buffer(make([]byte, 64*1024), 1024*1024)
+		for sc.Scan() {
+			line := sc.Text()
+			if n, ok := strings.CutPrefix(line, marker); ok {
+				fmt.Sscan(n, &current)
+				continue
+			}
+			emit(current, line)
+		}
+		io.Copy(io.Discard, pr) // a line too long for the scanner mustn't block the program
+	}()
+	err = cmd.Wait()
+	pw.Close()
+	<-done
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// Capture runs src and stores the output of every lesson in its Output field.
+// The output collected so far is kept even if the program fails.
+func Capture(ctx context.Context, src []byte, lessons []Lesson) error {
+	out := make([]strings.Builder, len(lessons))
+	err := Run(ctx, src, lessons, func(lesson int, line string) {
+		if lesson >= 0 && lesson < len(out) {
+			out[lesson].WriteString(line + "\n")
+		}
+	})
+	for i := range lessons {
+		lessons[i].Output = out[i].String()
+	}
+	return err
+}
+
+// instrument inserts a lessonMarker call in front of the first statement of
+// every lesson, on the same line so that line numbers in panics still match.
+func instrument(src []byte, lessons []Lesson) []byte {
+	var b strings.Builder
+	last := 0
+	for i, l := range lessons {
+		if l.stmtOffset < 0 {
+			continue
+		}
+		b.Write(src[last:l.stmtOffset])
+		fmt.Fprintf(&b, "lessonMarker(%d); ", i)
+		last = l.stmtOffset
+	}
+	b.Write(src[last:])
+	return []byte(b.String())
+}
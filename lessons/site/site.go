@@ -0,0 +1,124 @@
+// Package site renders lessons as HTML pages: a contents page and a page per
+// lesson with its narration, code and output. The static site of command
+// sitegen and the pages of the web server are the same templates; only the
+// links differ, since sitegen writes files and the server has routes.
+//
+// The templates and the stylesheet are built into the package. During
+// development they can be read from Dir instead, so that edits show up
+// without a rebuild.
+package site
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"strings"
+
+	"github.com/raproid/go-training/lessons"
+)
+
+//go:embed templates static
+var files embed.FS
+
+// Dir is where the templates and static files are in the repository,
+// relative to its root.
+const Dir = "lessons/site"
+
+// Files returns the built-in templates and static files: templates/*.html
+// and static/*.
+func Files() fs.FS {
+	return files
+}
+
+// Links says where the pages point to.
+type Links struct {
+	Index  string                   // the contents
+	Lesson func(slug string) string // the page of a lesson
+	Static func(name string) string // a file of the static directory
+	Live   func(slug string) string // the live output of a lesson; nil if there is none
+	Events func(slug string) string // the event stream behind Live; nil if there is none
+}
+
+// Index is what the contents page shows.
+type Index struct {
+	Source  string // the file name of the tutorial
+	Lessons []lessons.Lesson
+}
+
+// Lesson is what a lesson page shows.
+type Lesson struct {
+	lessons.Lesson
+	Number     int    // starting at 1
+	Source     string // the file name of the tutorial
+	Pending    bool   // the tutorial is still running, so there's no output yet
+	Prev, Next *lessons.Lesson
+}
+
+// NewLesson returns the page of ls[i].
+func NewLesson(ls []lessons.Lesson, i int, source string) Lesson {
+	p := Lesson{Lesson: ls[i], Number: i + 1, Source: source}
+	if i > 0 {
+		p.Prev = &ls[i-1]
+	}
+	if i < len(ls)-1 {
+		p.Next = &ls[i+1]
+	}
+	return p
+}
+
+// Paragraphs splits narration at blank lines.
+func Paragraphs(s string) []string {
+	return strings.Split(s, "\n\n")
+}
+
+// pages are the templates in templates/ that are whole pages; each is
+// combined with layout.html.
+var pages = []string{"index", "lesson"}
+
+// Templates are the parsed pages.
+type Templates struct {
+	pages map[string]*template.Template
+}
+
+// Parse parses the templates in fsys, which is laid out like Files.
+func Parse(fsys fs.FS, links Links) (*Templates, error) {
+	funcs := template.FuncMap{
+		"paragraphs": Paragraphs,
+		"indexURL":   func() string { return links.Index },
+		"lessonURL":  links.Lesson,
+		"staticURL":  links.Static,
+		"liveURL": func(slug string) string {
+			if links.Live == nil {
+				return ""
+			}
+			return links.Live(slug)
+		},
+	}
+	t := &Templates{pages: map[string]*template.Template{}}
+	for _, name := range pages {
+		p, err := template.New(name).Funcs(funcs).ParseFS(fsys, "templates/layout.html", "templates/"+name+".html")
+		if err != nil {
+			return nil, err
+		}
+		t.pages[name] = p
+	}
+	return t, nil
+}
+
+// Render writes the page name, "index" with an Index or "lesson" with a
+// Lesson, to w. Nothing is written if the template fails.
+func (t *Templates) Render(w io.Writer, name string, data any) error {
+	p, ok := t.pages[name]
+	if !ok {
+		return fmt.Errorf("site: no page %q", name)
+	}
+	var buf bytes.Buffer
+	if err := p.ExecuteTemplate(&buf, "layout", data); err != nil {
+		return err
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
@@ -0,0 +1,121 @@
+package site
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/raproid/go-training/lessons"
+)
+
+var testLessons = []lessons.Lesson{
+	{Slug: "intro", Title: "intro", Narration: "intro: hello\n\nsecond paragraph", Code: `fmt.Println("<b>")`, StartLine: 3, EndLine: 4, Output: "<b>\n"},
+	{Slug: "float", Title: "float", Narration: "float: numbers", StartLine: 6, EndLine: 6},
+}
+
+var fileLinks = Links{
+	Index:  "index.html",
+	Lesson: func(slug string) string { return slug + ".html" },
+	Static: func(name string) string { return name },
+}
+
+var routeLinks = Links{
+	Index:  "/",
+	Lesson: func(slug string) string { return "/lessons/" + slug },
+	Static: func(name string) string { return "/static/" + name },
+	Live:   func(slug string) string { return "/lessons/" + slug + "/live" },
+}
+
+func render(t *testing.T, links Links, name string, data any) string {
+	t.Helper()
+	tmpl, err := Parse(Files(), links)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := tmpl.Render(&b, name, data); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func checkContains(t *testing.T, page string, want ...string) {
+	t.Helper()
+	for _, w := range want {
+		if !strings.Contains(page, w) {
+			t.Errorf("page doesn't contain %q:\n%s", w, page)
+		}
+	}
+}
+
+func TestIndex(t *testing.T) {
+	index := Index{Source: "test.go", Lessons: testLessons}
+	checkContains(t, render(t, fileLinks, "index", index),
+		`<link rel="stylesheet" href="style.css">`,
+		`<li><a href="intro.html">intro</a> <small>lines 3–4</small></li>`,
+		`<li><a href="float.html">float</a>`,
+	)
+	checkContains(t, render(t, routeLinks, "index", index),
+		`<link rel="stylesheet" href="/static/style.css">`,
+		`<header><a href="/">Go basics</a></header>`,
+		`<li><a href="/lessons/intro">intro</a>`,
+	)
+}
+
+func TestLesson(t *testing.T) {
+	first := NewLesson(testLessons, 0, "test.go")
+	if first.Number != 1 || first.Prev != nil || first.Next != &testLessons[1] {
+		t.Errorf("NewLesson(0) = number %d, prev %v, next %v", first.Number, first.Prev, first.Next)
+	}
+	page := render(t, fileLinks, "lesson", first)
+	checkContains(t, page,
+		`<title>intro · Go basics</title>`,
+		"<p>intro: hello</p>\n<p>second paragraph</p>",
+		`<h2>Code <small>test.go:3–4</small></h2>`,
+		`<pre><code class="language-go">fmt.Println(&#34;&lt;b&gt;&#34;)</code></pre>`,
+		"<h2>Output</h2>\n<pre class=\"output\">&lt;b&gt;\n</pre>",
+		`<a rel="next" href="float.html">float →</a>`,
+	)
+	if strings.Contains(page, "run live") || strings.Contains(page, `rel="prev"`) {
+		t.Errorf("the first lesson of the static site has a live link or a previous lesson:\n%s", page)
+	}
+
+	last := NewLesson(testLessons, 1, "test.go")
+	page = render(t, fileLinks, "lesson", last)
+	if strings.Contains(page, "<h2>Code") || strings.Contains(page, "<h2>Output") || strings.Contains(page, `rel="next"`) {
+		t.Errorf("a lesson without code, output or next lesson shows them:\n%s", page)
+	}
+
+	last.Pending = true
+	checkContains(t, render(t, routeLinks, "lesson", last),
+		`<h2>Output <small><a href="/lessons/float/live">run live</a></small></h2>`,
+		`still running`,
+		`<a rel="prev" href="/lessons/intro">← intro</a>`,
+	)
+}
+
+func TestRenderErrors(t *testing.T) {
+	tmpl, err := Parse(Files(), fileLinks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := tmpl.Render(&b, "nope", nil); err == nil {
+		t.Error("rendering an unknown page: no error")
+	}
+	if err := tmpl.Render(&b, "lesson", Index{}); err == nil {
+		t.Error("rendering a lesson with the wrong data: no error")
+	}
+	if b.Len() != 0 {
+		t.Errorf("failed renders wrote %q", b.String())
+	}
+
+	broken := fstest.MapFS{
+		"templates/layout.html": {Data: []byte(`{{define "layout"}}{{template "content" .}}{{end}}`)},
+		"templates/index.html":  {Data: []byte(`{{define "content"}}{{.Missing}{{end}}`)},
+		"templates/lesson.html": {Data: []byte(`{{define "content"}}{{end}}`)},
+	}
+	if _, err := Parse(broken, fileLinks); err == nil {
+		t.Error("parsing a broken template: no error")
+	}
+}
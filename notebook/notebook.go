@@ -0,0 +1,177 @@
+// Package notebook reads and writes Markdown notebooks of Go snippets.
+//
+// A notebook is a Markdown file in which every ```go fenced block is a cell.
+// Running a cell stores what it printed in an ```output block right after it;
+// everything else in the file is kept as written:
+//
+//	Slices share their underlying array:
+//
+//	```go
+//	s := []int{1, 2, 3}
+//	t := s
+//	t[0] = 100
+//	fmt.Println(s)
+//	```
+//
+//	```output
+//	[100 2 3]
+//	```
+//
+// As in CommonMark, a block may be fenced with more than three backticks and
+// ends at a line of at least as many; Format uses a fence longer than any run
+// of backticks in the code or output, so printing ``` can't end a block early.
+package notebook
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+)
+
+// Cell is a Go snippet and the output of its last run.
+type Cell struct {
+	Line      int // line of the opening fence, starting at 1
+	Code      string
+	Output    string
+	HasOutput bool // whether the notebook has an output block for the cell
+}
+
+// Notebook is a parsed notebook; Format turns it back into Markdown.
+type Notebook struct {
+	Cells []*Cell
+
+	// parts interleaves the Markdown between cells (strings) with the cells themselves (*Cell)
+	parts []any
+}
+
+const (
+	codeInfo   = "go"
+	outputInfo = "output"
+)
+
+// Parse splits a Markdown notebook into cells.
+func Parse(src []byte) *Notebook {
+	nb := &Notebook{}
+	var text strings.Builder
+	flushText := func() {
+		if text.Len() > 0 {
+			nb.parts = append(nb.parts, text.String())
+			text.Reset()
+		}
+	}
+
+	var lines []string
+	sc := bufio.NewScanner(bytes.NewReader(src))
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		lines = append(lines, sc.Text())
+	}
+
+	for i := 0; i < len(lines); i++ {
+		n, info := openingFence(lines[i])
+		if n == 0 {
+			text.WriteString(lines[i] + "\n")
+			continue
+		}
+		if info != codeInfo {
+			// another fenced block is text, even if it shows a ```go line
+			_, end := block(lines, i+1, n)
+			end = min(end, len(lines)-1)
+			for _, line := range lines[i : end+1] {
+				text.WriteString(line + "\n")
+			}
+			i = end
+			continue
+		}
+		cell := &Cell{Line: i + 1}
+		cell.Code, i = block(lines, i+1, n)
+
+		// an output block may follow after blank lines; it belongs to the cell and is replaced on every run
+		j := i + 1
+		for j < len(lines) && strings.TrimSpace(lines[j]) == "" {
+			j++
+		}
+		if j < len(lines) {
+			if n, info := openingFence(lines[j]); info == outputInfo {
+				cell.Output, i = block(lines, j+1, n)
+				cell.HasOutput = true
+			}
+		}
+
+		flushText()
+		nb.parts = append(nb.parts, cell)
+		nb.Cells = append(nb.Cells, cell)
+	}
+	flushText()
+	return nb
+}
+
+// openingFence returns the number of backticks that open a fenced block on
+// line, at least three, and the info string after them, such as go.
+func openingFence(line string) (int, string) {
+	line = strings.TrimSpace(line)
+	n := len(line) - len(strings.TrimLeft(line, "`"))
+	if n < 3 {
+		return 0, ""
+	}
+	return n, strings.TrimSpace(line[n:])
+}
+
+// block returns the lines from start up to the fence that closes a block
+// opened with n backticks, and the index of that fence; an unclosed block
+// runs to the end of the file.
+func block(lines []string, start, n int) (string, int) {
+	end := start
+	for end < len(lines) && !closes(lines[end], n) {
+		end++
+	}
+	return strings.Join(lines[start:end], "\n"), end
+}
+
+// closes reports whether line is a closing fence for a block opened with n
+// backticks: nothing but at least n backticks.
+func closes(line string, n int) bool {
+	line = strings.TrimSpace(line)
+	return len(line) >= n && strings.Trim(line, "`") == ""
+}
+
+// fence returns a fence longer than any run of backticks in s, so that s
+// can't close it.
+func fence(s string) string {
+	n := 3
+	for strings.Contains(s, strings.Repeat("`", n)) {
+		n++
+	}
+	return strings.Repeat("`", n)
+}
+
+// Format renders the notebook as Markdown, with an output block after every
+// cell that has output.
+func (nb *Notebook) Format() []byte {
+	var b bytes.Buffer
+	for _, p := range nb.parts {
+		switch p := p.(type) {
+		case string:
+			b.WriteString(p)
+		case *Cell:
+			f := fence(p.Code)
+			b.WriteString(f + codeInfo + "\n")
+			writeBlock(&b, p.Code)
+			b.WriteString(f + "\n")
+			if p.HasOutput {
+				f := fence(p.Output)
+				b.WriteString("\n" + f + outputInfo + "\n")
+				writeBlock(&b, p.Output)
+				b.WriteString(f + "\n")
+			}
+		}
+	}
+	return b.Bytes()
+}
+
+func writeBlock(b *bytes.Buffer, s string) {
+	s = strings.TrimRight(s, "\n")
+	if s != "" {
+		b.WriteString(s + "\n")
+	}
+}
@@ -0,0 +1,115 @@
+package notebook
+
+import (
+	"strings"
+	"testing"
+)
+
+const slices = "Slices share their underlying array:\n" +
+	"\n" +
+	"```go\n" +
+	"s := []int{1, 2, 3}\n" +
+	"t := s\n" +
+	"t[0] = 100\n" +
+	"fmt.Println(s)\n" +
+	"```\n" +
+	"\n" +
+	"```output\n" +
+	"[100 2 3]\n" +
+	"```\n" +
+	"\n" +
+	"Not a cell:\n" +
+	"\n" +
+	"```text\n" +
+	"```go\n" +
+	"```\n" +
+	"\n" +
+	"```go\n" +
+	"fmt.Println(len(\"\"))\n" +
+	"```\n"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		cells []Cell
+	}{
+		{"no cells", "# Notes\n\nJust text.\n", nil},
+		{"cells", slices, []Cell{
+			{Line: 3, Code: "s := []int{1, 2, 3}\nt := s\nt[0] = 100\nfmt.Println(s)", Output: "[100 2 3]", HasOutput: true},
+			{Line: 20, Code: `fmt.Println(len(""))`},
+		}},
+		{"indented fences", "  ```go\nfmt.Println(1)\n  ```\n\n\n  ```output  \n1\n```\n", []Cell{
+			{Line: 1, Code: "fmt.Println(1)", Output: "1", HasOutput: true},
+		}},
+		{"longer fences", "````go\nfmt.Println(\"```\")\n```\n````\n\n`````output\n```\n````\n`````\n", []Cell{
+			{Line: 1, Code: "fmt.Println(\"```\")\n```", Output: "```\n````", HasOutput: true},
+		}},
+		{"closed by a longer fence", "```go\nx := 1\n`````\n", []Cell{
+			{Line: 1, Code: "x := 1"},
+		}},
+		{"unclosed", "```go\nfmt.Println(1)\n", []Cell{
+			{Line: 1, Code: "fmt.Println(1)"},
+		}},
+		{"two backticks", "``go\nfmt.Println(1)\n``\n", nil},
+		{"output without a cell", "```output\n1\n```\n", nil},
+		{"text between the cell and its output", "```go\nx := 1\n```\nthen\n```output\n1\n```\n", []Cell{
+			{Line: 1, Code: "x := 1"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nb := Parse([]byte(tt.src))
+			if len(nb.Cells) != len(tt.cells) {
+				t.Fatalf("%d cells, want %d", len(nb.Cells), len(tt.cells))
+			}
+			for i, c := range nb.Cells {
+				if *c != tt.cells[i] {
+					t.Errorf("cell %d = %+v, want %+v", i+1, *c, tt.cells[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	// a notebook that Format wrote comes back unchanged
+	for _, src := range []string{slices, "# Notes\n", "", "````go\nfmt.Println(\"```\")\n````\n"} {
+		if got := string(Parse([]byte(src)).Format()); got != src {
+			t.Errorf("Format(Parse(%q)) = %q", src, got)
+		}
+	}
+
+	nb := Parse([]byte("Before\n```go\nfmt.Println(1)\n```\nAfter\n"))
+	nb.Cells[0].Output, nb.Cells[0].HasOutput = "1\n\n", true
+	want := "Before\n```go\nfmt.Println(1)\n```\n\n```output\n1\n```\nAfter\n"
+	if got := string(nb.Format()); got != want {
+		t.Errorf("Format added the output as\n%s\nwant\n%s", got, want)
+	}
+}
+
+// TestFormatBackticks checks that output with fences in it stays inside its
+// block, however often the cell runs.
+func TestFormatBackticks(t *testing.T) {
+	output := "```\nnot the end\n````go\n```output"
+	src := "```go\nfmt.Println(\"```\")\n```\n\nAfter\n"
+	for run := 1; run <= 3; run++ {
+		nb := Parse([]byte(src))
+		if len(nb.Cells) != 1 {
+			t.Fatalf("run %d: %d cells, want 1", run, len(nb.Cells))
+		}
+		c := nb.Cells[0]
+		if run > 1 && c.Output != output {
+			t.Errorf("run %d: output %q, want %q", run, c.Output, output)
+		}
+		c.Output, c.HasOutput = output, true
+		next := string(nb.Format())
+		if run > 2 && next != src {
+			t.Errorf("run %d changed the notebook from\n%s\nto\n%s", run, src, next)
+		}
+		src = next
+	}
+	if !strings.Contains(src, "\n`````output\n") || !strings.HasSuffix(src, "\n`````\n\nAfter\n") {
+		t.Errorf("the output isn't fenced with five backticks:\n%s", src)
+	}
+}
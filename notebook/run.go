@@ -0,0 +1,183 @@
+package notebook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/raproid/go-training/internal/tempmod"
+)
+
+// stdPackages are imported automatically when a cell uses them without an import.
+var stdPackages = map[string]string{
+	"bufio":   "bufio",
+	"bytes":   "bytes",
+	"context": "context",
+	"errors":  "errors",
+	"fmt":     "fmt",
+	"http":    "net/http",
+	"io":      "io",
+	"json":    "encoding/json",
+	"log":     "log",
+	"maps":    "maps",
+	"math":    "math",
+	"os":      "os",
+	"rand":    "math/rand",
+	"reflect": "reflect",
+	"slices":  "slices",
+	"sort":    "sort",
+	"strconv": "strconv",
+	"strings": "strings",
+	"sync":    "sync",
+	"time":    "time",
+	"unicode": "unicode",
+	"utf8":    "unicode/utf8",
+}
+
+// Runner runs cells, each as its own program in a fresh temporary module.
+type Runner struct {
+	Timeout time.Duration // for building and running a single cell
+}
+
+// Run runs the cell and stores what it printed, or why it failed, in its Output.
+func (r *Runner) Run(ctx context.Context, c *Cell) error {
+	ctx, cancel := context.WithTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	src, bodyLine := wrap(c.Code)
+	dir, err := tempmod.Create("cell", map[string][]byte{"main.go": src})
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	c.HasOutput = true
+	build := tempmod.Command(ctx, dir, "build", "-o", "cell", ".")
+	if out, err := build.CombinedOutput(); err != nil {
+		if ctx.Err() != nil {
+			c.Output = fmt.Sprintf("build timed out after %v", r.Timeout)
+			return nil
+		}
+		c.Output = "build failed:\n" + cellPositions(string(out), bodyLine)
+		return nil
+	}
+
+	// the program only sees its own directory and a minimal environment
+	run := exec.CommandContext(ctx, filepath.Join(dir, "cell"))
+	run.Dir = dir
+	run.Env = []string{"HOME=" + dir, "TMPDIR=" + dir, "PATH=/usr/bin:/bin"}
+	var out bytes.Buffer
+	run.Stdout = &out
+	run.Stderr = &out
+	err = run.Run()
+	c.Output = strings.TrimRight(out.String(), "\n")
+	var status string
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() != nil:
+		status = fmt.Sprintf("(timed out after %v)", r.Timeout)
+	case errors.As(err, &exitErr):
+		status = fmt.Sprintf("(%v)", exitErr)
+	case err != nil:
+		return err
+	}
+	if status != "" && c.Output != "" {
+		c.Output += "\n"
+	}
+	c.Output += status
+	return nil
+}
+
+// wrap turns a cell into a main package: import lines at the top of the cell
+// are kept as imports, the rest becomes the body of main(), and standard
+// packages the cell uses without importing them are added. It also returns
+// the line of main.go that holds the first line of the cell.
+func wrap(code string) ([]byte, int) {
+	lines := strings.Split(code, "\n")
+	var imports []string
+	i := 0
+header:
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		switch {
+		case line == "" || strings.HasPrefix(line, "//"):
+			imports = append(imports, lines[i])
+		case strings.HasPrefix(line, "import ("):
+			for ; i < len(lines) && strings.TrimSpace(lines[i]) != ")"; i++ {
+				imports = append(imports, lines[i])
+			}
+			imports = append(imports, ")")
+		case strings.HasPrefix(line, "import "):
+			imports = append(imports, lines[i])
+		default:
+			break header
+		}
+	}
+	body := lines[i:]
+
+	build := func(extra []string) ([]byte, int) {
+		var b strings.Builder
+		b.WriteString("package main\n\n")
+		for _, path := range extra {
+			b.WriteString("import " + strconv.Quote(path) + "\n")
+		}
+		b.WriteString(strings.Join(imports, "\n") + "\n\nfunc main() {\n")
+		bodyLine := strings.Count(b.String(), "\n") + 1 - i
+		b.WriteString(strings.Join(body, "\n") + "\n}\n")
+		return []byte(b.String()), bodyLine
+	}
+
+	src, bodyLine := build(nil)
+	f, err := parser.ParseFile(token.NewFileSet(), "main.go", src, 0)
+	if err != nil {
+		// let the compiler report it, with positions it can map back to the cell
+		return src, bodyLine
+	}
+	imported := map[string]bool{}
+	for _, spec := range f.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		imported[path] = true
+	}
+	// Unresolved holds the identifiers declared nowhere in the file, which includes package names
+	missing := map[string]bool{}
+	for _, id := range f.Unresolved {
+		if path, ok := stdPackages[id.Name]; ok && !imported[path] {
+			missing[path] = true
+		}
+	}
+	extra := make([]string, 0, len(missing))
+	for path := range missing {
+		extra = append(extra, path)
+	}
+	sort.Strings(extra)
+	return build(extra)
+}
+
+var compilerPos = regexp.MustCompile(`\./main\.go:(\d+)(:\d+)?`)
+
+// cellPositions rewrites main.go:LINE in compiler messages to the line within the cell.
+func cellPositions(out string, bodyLine int) string {
+	out = compilerPos.ReplaceAllStringFunc(out, func(m string) string {
+		sub := compilerPos.FindStringSubmatch(m)
+		n, _ := strconv.Atoi(sub[1])
+		return "cell:" + strconv.Itoa(n-bodyLine+1) + sub[2]
+	})
+	var kept []string
+	for _, line := range strings.Split(strings.TrimRight(out, "\n"), "\n") {
+		if !strings.HasPrefix(line, "# ") {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
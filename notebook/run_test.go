@@ -0,0 +1,62 @@
+package notebook
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCellPositions(t *testing.T) {
+	tests := []struct {
+		name     string
+		out      string
+		bodyLine int
+		want     string
+	}{
+		{
+			name:     "line and column",
+			out:      "# cell\n./main.go:8:2: declared and not used: x\n",
+			bodyLine: 6,
+			want:     "cell:3:2: declared and not used: x",
+		},
+		{
+			name:     "line only, several messages",
+			out:      "# cell\n./main.go:5: syntax error\n./main.go:12:14: undefined: y\n",
+			bodyLine: 5,
+			want:     "cell:1: syntax error\ncell:8:14: undefined: y",
+		},
+		{
+			name:     "no positions",
+			out:      "go: cannot find main module\n",
+			bodyLine: 5,
+			want:     "go: cannot find main module",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cellPositions(tt.out, tt.bodyLine); got != tt.want {
+				t.Errorf("cellPositions = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestWrapPositions checks that the line wrap returns is where the cell starts
+// in main.go, with and without imports added for it.
+func TestWrapPositions(t *testing.T) {
+	for _, code := range []string{
+		"x := 1\nfmt.Println(x)",
+		"import \"os\"\n\nos.Exit(0)",
+		"x := 1",
+	} {
+		src, bodyLine := wrap(code)
+		lines := strings.Split(string(src), "\n")
+		first := strings.Split(code, "\n")[0]
+		if bodyLine < 1 || bodyLine > len(lines) {
+			t.Errorf("wrap(%q): line %d, but main.go has %d lines", code, bodyLine, len(lines))
+			continue
+		}
+		if first != "import \"os\"" && lines[bodyLine-1] != first {
+			t.Errorf("wrap(%q): line %d of main.go is %q, want %q\n%s", code, bodyLine, lines[bodyLine-1], first, src)
+		}
+	}
+}
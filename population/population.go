@@ -0,0 +1,346 @@
+// Package population turns the single numbers of the statePopulations map in
+// test.go into yearly series and computes how they change: year-over-year
+// growth, compound annual growth (CAGR), linear and exponential projections
+// and changes in the ranking of the regions.
+//
+// Series are usually read from CSV, in either of two layouts. Long, one row
+// per region and year:
+//
+//	state,year,population
+//	CA,2015,39144818
+//	CA,2016,39250017
+//
+// or wide, one row per region and a column per year:
+//
+//	state,2015,2016
+//	CA,39144818,39250017
+package population
+
+import (
+	"cmp"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Point is the population of a region in a year.
+type Point struct {
+	Year       int
+	Population int64
+}
+
+// Series is the population of one region over the years, sorted by year.
+type Series struct {
+	Name   string
+	Points []Point
+}
+
+// Dataset holds the series of several regions by name.
+type Dataset map[string]*Series
+
+// Names returns the names of the regions in d, sorted.
+func (d Dataset) Names() []string {
+	return slices.Sorted(maps.Keys(d))
+}
+
+// Add records the population of name in year, replacing an earlier value for
+// the same year.
+func (d Dataset) Add(name string, year int, population int64) {
+	s, ok := d[name]
+	if !ok {
+		s = &Series{Name: name}
+		d[name] = s
+	}
+	s.Set(year, population)
+}
+
+// Set records the population in year, keeping the points sorted.
+func (s *Series) Set(year int, population int64) {
+	i, found := slices.BinarySearchFunc(s.Points, year, func(p Point, year int) int { return cmp.Compare(p.Year, year) })
+	if found {
+		s.Points[i].Population = population
+		return
+	}
+	s.Points = slices.Insert(s.Points, i, Point{year, population})
+}
+
+// Value returns the population in year, if the series has it.
+func (s *Series) Value(year int) (int64, bool) {
+	i, found := slices.BinarySearchFunc(s.Points, year, func(p Point, year int) int { return cmp.Compare(p.Year, year) })
+	if !found {
+		return 0, false
+	}
+	return s.Points[i].Population, true
+}
+
+// First and Last return the earliest and the latest point; the series must not be empty.
+func (s *Series) First() Point { return s.Points[0] }
+func (s *Series) Last() Point  { return s.Points[len(s.Points)-1] }
+
+// Growth is the change between two points of a series.
+type Growth struct {
+	From, To int     // years
+	Rate     float64 // per year, 0.01 is 1%; annualized if the years aren't adjacent
+}
+
+// YearOverYear returns the growth between each pair of consecutive points.
+// Where years are missing, the rate is annualized over the gap.
+func (s *Series) YearOverYear() []Growth {
+	var list []Growth
+	for i := 1; i < len(s.Points); i++ {
+		a, b := s.Points[i-1], s.Points[i]
+		if rate, err := cagr(a, b); err == nil {
+			list = append(list, Growth{a.Year, b.Year, rate})
+		}
+	}
+	return list
+}
+
+// CAGR returns the compound annual growth rate between two years:
+// (to/from)^(1/years) - 1.
+func (s *Series) CAGR(from, to int) (float64, error) {
+	a, ok := s.Value(from)
+	if !ok {
+		return 0, fmt.Errorf("population: %s has no value for %d", s.Name, from)
+	}
+	b, ok := s.Value(to)
+	if !ok {
+		return 0, fmt.Errorf("population: %s has no value for %d", s.Name, to)
+	}
+	return cagr(Point{from, a}, Point{to, b})
+}
+
+func cagr(a, b Point) (float64, error) {
+	if a.Year == b.Year {
+		return 0, errors.New("population: growth needs two different years")
+	}
+	if a.Population <= 0 || b.Population <= 0 {
+		return 0, errors.New("population: growth needs positive populations")
+	}
+	return math.Pow(float64(b.Population)/float64(a.Population), 1/float64(b.Year-a.Year)) - 1, nil
+}
+
+// Method is a way of projecting a series.
+type Method int
+
+const (
+	Linear      Method = iota // a straight line fitted to the points
+	Exponential               // constant growth, a straight line fitted to the logarithms
+)
+
+func (m Method) String() string {
+	switch m {
+	case Linear:
+		return "linear"
+	case Exponential:
+		return "exponential"
+	}
+	return "Method(" + strconv.Itoa(int(m)) + ")"
+}
+
+// ParseMethod returns the method with the given name.
+func ParseMethod(name string) (Method, error) {
+	for _, m := range []Method{Linear, Exponential} {
+		if strings.EqualFold(name, m.String()) {
+			return m, nil
+		}
+	}
+	return 0, fmt.Errorf("population: unknown projection method %q", name)
+}
+
+// Project estimates the population in year by fitting the method's curve to
+// every point of the series with least squares. It needs at least two years.
+// Linear projections of a shrinking population stop at 0.
+func (s *Series) Project(year int, m Method) (float64, error) {
+	if len(s.Points) < 2 {
+		return 0, fmt.Errorf("population: %s needs at least two years to project", s.Name)
+	}
+	xs := make([]float64, len(s.Points))
+	ys := make([]float64, len(s.Points))
+	for i, p := range s.Points {
+		xs[i] = float64(p.Year)
+		ys[i] = float64(p.Population)
+		if m == Exponential {
+			if p.Population <= 0 {
+				return 0, fmt.Errorf("population: %s has a non-positive population in %d, can't project exponentially", s.Name, p.Year)
+			}
+			ys[i] = math.Log(ys[i])
+		}
+	}
+	slope, intercept := fit(xs, ys)
+	y := slope*float64(year) + intercept
+	switch m {
+	case Linear:
+		return max(y, 0), nil // a falling line would cross zero eventually
+	case Exponential:
+		return math.Exp(y), nil
+	}
+	return 0, fmt.Errorf("population: unknown projection method %v", m)
+}
+
+// fit returns the least squares line through the points; the years are
+// centered first to keep the sums small.
+func fit(xs, ys []float64) (slope, intercept float64) {
+	var mx, my float64
+	for i := range xs {
+		mx += xs[i]
+		my += ys[i]
+	}
+	n := float64(len(xs))
+	mx, my = mx/n, my/n
+	var sxy, sxx float64
+	for i := range xs {
+		sxy += (xs[i] - mx) * (ys[i] - my)
+		sxx += (xs[i] - mx) * (xs[i] - mx)
+	}
+	slope = sxy / sxx
+	return slope, my - slope*mx
+}
+
+// Rank returns the names of the regions that have a value for year, largest
+// population first; ties are broken by name.
+func (d Dataset) Rank(year int) []string {
+	var names []string
+	for _, name := range d.Names() {
+		if _, ok := d[name].Value(year); ok {
+			names = append(names, name)
+		}
+	}
+	slices.SortStableFunc(names, func(a, b string) int {
+		pa, _ := d[a].Value(year)
+		pb, _ := d[b].Value(year)
+		return cmp.Compare(pb, pa)
+	})
+	return names
+}
+
+// RankChange is how a region moved in the ranking between two years. Ranks
+// start at 1.
+type RankChange struct {
+	Name     string
+	From, To int
+}
+
+// Moved returns how many places the region went up; negative is down.
+func (c RankChange) Moved() int {
+	return c.From - c.To
+}
+
+// RankChanges compares the rankings of two years for the regions that have
+// values in both, in the order of the later ranking.
+func (d Dataset) RankChanges(from, to int) []RankChange {
+	both := Dataset{}
+	for name, s := range d {
+		_, ok1 := s.Value(from)
+		_, ok2 := s.Value(to)
+		if ok1 && ok2 {
+			both[name] = s
+		}
+	}
+	fromRank := map[string]int{}
+	for i, name := range both.Rank(from) {
+		fromRank[name] = i + 1
+	}
+	var list []RankChange
+	for i, name := range both.Rank(to) {
+		list = append(list, RankChange{Name: name, From: fromRank[name], To: i + 1})
+	}
+	return list
+}
+
+// ReadCSV reads series in the long or the wide layout described in the
+// package documentation. The first column is the region in both; the header
+// decides the layout.
+func ReadCSV(r io.Reader) (Dataset, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("population: reading the CSV header: %w", err)
+	}
+	if len(header) < 2 {
+		return nil, errors.New("population: the CSV needs a region column and at least one more")
+	}
+
+	// wide if every column after the first is a year
+	years := make([]int, len(header))
+	wide := true
+	for i, h := range header[1:] {
+		y, err := strconv.Atoi(strings.TrimSpace(h))
+		if err != nil {
+			wide = false
+			break
+		}
+		years[i+1] = y
+	}
+	yearCol, popCol := -1, -1
+	if !wide {
+		for i, h := range header {
+			switch strings.ToLower(strings.TrimSpace(h)) {
+			case "year":
+				yearCol = i
+			case "population", "pop", "value":
+				popCol = i
+			}
+		}
+		if yearCol < 1 || popCol < 1 {
+			return nil, fmt.Errorf("population: the CSV header %q has neither year columns nor year and population columns", strings.Join(header, ","))
+		}
+	}
+
+	d := Dataset{}
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("population: %w", err)
+		}
+		line, _ := cr.FieldPos(0)
+		name := strings.TrimSpace(rec[0])
+		if name == "" {
+			return nil, fmt.Errorf("population: line %d: no region", line)
+		}
+		if wide {
+			for i := 1; i < len(rec); i++ {
+				if strings.TrimSpace(rec[i]) == "" {
+					continue // no value for that year
+				}
+				n, err := parseCount(rec[i])
+				if err != nil {
+					return nil, fmt.Errorf("population: line %d, %d: %v", line, years[i], err)
+				}
+				d.Add(name, years[i], n)
+			}
+			continue
+		}
+		year, err := strconv.Atoi(strings.TrimSpace(rec[yearCol]))
+		if err != nil {
+			return nil, fmt.Errorf("population: line %d: invalid year %q", line, rec[yearCol])
+		}
+		n, err := parseCount(rec[popCol])
+		if err != nil {
+			return nil, fmt.Errorf("population: line %d: %v", line, err)
+		}
+		d.Add(name, year, n)
+	}
+	return d, nil
+}
+
+// parseCount parses a population, allowing thousands separators like 39,250,017
+// (quoted in CSV) or 39_250_017.
+func parseCount(s string) (int64, error) {
+	clean := strings.NewReplacer(",", "", "_", "", " ", "").Replace(s)
+	n, err := strconv.ParseInt(clean, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid population %q", s)
+	}
+	return n, nil
+}
@@ -0,0 +1,215 @@
+package population
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const long = `state,year,population
+CA,2015,39144818
+CA,2016,39250017
+TX,2015,27469114
+TX,2016,27862596
+FL,2015,20271272
+FL,2016,20612439
+`
+
+const wide = `state, 2015, 2016
+CA, 39144818, 39250017
+TX, 27469114, 27862596
+FL, "20,271,272", 20_612_439
+`
+
+func read(t *testing.T, src string) Dataset {
+	t.Helper()
+	d, err := ReadCSV(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9*math.Max(1, math.Abs(b))
+}
+
+func TestReadCSV(t *testing.T) {
+	want := read(t, long)
+	if names := want.Names(); !slices.Equal(names, []string{"CA", "FL", "TX"}) {
+		t.Fatalf("Names() = %v", names)
+	}
+	for name, src := range map[string]string{
+		"wide":                 wide,
+		"long, other columns":  "state,note,Pop,Year\nCA,,39250017,2016\nCA,x,39144818,2015\nTX,,27469114,2015\nTX,,27862596,2016\nFL,,20612439,2016\nFL,,20271272,2015\n",
+		"long, repeated years": long + "CA,2016,39250017\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			d := read(t, src)
+			if !slices.Equal(d.Names(), want.Names()) {
+				t.Fatalf("Names() = %v, want %v", d.Names(), want.Names())
+			}
+			for _, n := range want.Names() {
+				if !slices.Equal(d[n].Points, want[n].Points) {
+					t.Errorf("%s: %v, want %v", n, d[n].Points, want[n].Points)
+				}
+			}
+		})
+	}
+
+	d := read(t, "state,2016,2015,2017\nCA,39250017,39144818,\n")
+	if got := d["CA"].Points; !slices.Equal(got, []Point{{2015, 39144818}, {2016, 39250017}}) {
+		t.Errorf("wide columns out of order with an empty cell: %v", got)
+	}
+}
+
+func TestReadCSVErrors(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+	}{
+		{"empty", "", "population: reading the CSV header: EOF"},
+		{"one column", "state\nCA\n", "the CSV needs a region column and at least one more"},
+		{"no year column", "state,population\nCA,1\n", `the CSV header "state,population" has neither year columns nor year and population columns`},
+		{"region in the year column", "year,state,population\n2015,CA,1\n", "neither year columns"},
+		{"no region", "state,year,population\n,2015,1\n", "population: line 2: no region"},
+		{"bad year", "state,year,population\nCA,2015,1\nCA,soon,1\n", `population: line 3: invalid year "soon"`},
+		{"bad population", "state,year,population\nCA,2015,many\n", `population: line 2: invalid population "many"`},
+		{"negative population", "state,2015\nCA,-1\n", `population: line 2, 2015: invalid population "-1"`},
+		{"short row", "state,year,population\nCA,2015\n", "wrong number of fields"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadCSV(strings.NewReader(tt.src))
+			if err == nil {
+				t.Fatal("no error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %q, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCAGR(t *testing.T) {
+	s := &Series{Name: "CA"}
+	s.Set(2010, 1000)
+	s.Set(2012, 1210)
+	s.Set(2013, 0)
+	tests := []struct {
+		from, to int
+		want     float64
+		err      string
+	}{
+		{2010, 2012, 0.1, ""},
+		{2012, 2010, 0.1, ""},
+		{2010, 2010, 0, "growth needs two different years"},
+		{2010, 2013, 0, "growth needs positive populations"},
+		{2010, 2011, 0, "CA has no value for 2011"},
+		{2009, 2010, 0, "CA has no value for 2009"},
+	}
+	for _, tt := range tests {
+		got, err := s.CAGR(tt.from, tt.to)
+		switch {
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("CAGR(%d, %d) = %v, %v, want error %q", tt.from, tt.to, got, err, tt.err)
+		case tt.err == "" && (err != nil || !near(got, tt.want)):
+			t.Errorf("CAGR(%d, %d) = %v, %v, want %v", tt.from, tt.to, got, err, tt.want)
+		}
+	}
+
+	yoy := s.YearOverYear()
+	if len(yoy) != 1 || yoy[0].From != 2010 || yoy[0].To != 2012 || !near(yoy[0].Rate, 0.1) {
+		t.Errorf("YearOverYear() = %v, want 10%% a year from 2010 to 2012 and no rate to 0", yoy)
+	}
+}
+
+func TestProject(t *testing.T) {
+	line := &Series{Name: "line", Points: []Point{{2000, 100}, {2001, 110}, {2002, 120}}}
+	growth := &Series{Name: "growth", Points: []Point{{2000, 1000}, {2001, 1100}, {2002, 1210}}}
+	falling := &Series{Name: "falling", Points: []Point{{2000, 200}, {2001, 100}}}
+	tests := []struct {
+		s    *Series
+		year int
+		m    Method
+		want float64
+	}{
+		{line, 2003, Linear, 130},
+		{line, 1999, Linear, 90},
+		{line, 2001, Linear, 110},
+		{growth, 2003, Exponential, 1331},
+		{growth, 2000, Exponential, 1000},
+		{falling, 2010, Linear, 0},
+		{falling, 2002, Exponential, 50},
+	}
+	for _, tt := range tests {
+		got, err := tt.s.Project(tt.year, tt.m)
+		if err != nil || !near(got, tt.want) {
+			t.Errorf("%s: Project(%d, %v) = %v, %v, want %v", tt.s.Name, tt.year, tt.m, got, err, tt.want)
+		}
+	}
+
+	single := &Series{Name: "single", Points: []Point{{2000, 1}}}
+	zero := &Series{Name: "zero", Points: []Point{{2000, 0}, {2001, 10}}}
+	for _, tt := range []struct {
+		s   *Series
+		m   Method
+		err string
+	}{
+		{single, Linear, "single needs at least two years to project"},
+		{zero, Exponential, "zero has a non-positive population in 2000"},
+		{line, Method(7), "unknown projection method Method(7)"},
+	} {
+		if got, err := tt.s.Project(2010, tt.m); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: Project(%v) = %v, %v, want error %q", tt.s.Name, tt.m, got, err, tt.err)
+		}
+	}
+	if _, err := zero.Project(2010, Linear); err != nil {
+		t.Errorf("a linear projection from 0: %v", err)
+	}
+}
+
+func TestParseMethod(t *testing.T) {
+	for name, want := range map[string]Method{"linear": Linear, "Exponential": Exponential} {
+		if m, err := ParseMethod(name); err != nil || m != want {
+			t.Errorf("ParseMethod(%q) = %v, %v", name, m, err)
+		}
+	}
+	if _, err := ParseMethod("both"); err == nil {
+		t.Error("ParseMethod(both): no error")
+	}
+}
+
+func TestRankChanges(t *testing.T) {
+	d := Dataset{}
+	for _, p := range []struct {
+		name string
+		year int
+		pop  int64
+	}{
+		{"CA", 2000, 30}, {"CA", 2010, 30},
+		{"TX", 2000, 20}, {"TX", 2010, 40},
+		{"FL", 2000, 10}, {"FL", 2010, 30}, // ties with CA, which comes first by name
+		{"NY", 2010, 100}, // no value for 2000
+		{"OH", 2000, 5},   // no value for 2010
+	} {
+		d.Add(p.name, p.year, p.pop)
+	}
+
+	if got := d.Rank(2010); !slices.Equal(got, []string{"NY", "TX", "CA", "FL"}) {
+		t.Errorf("Rank(2010) = %v", got)
+	}
+	want := []RankChange{{"TX", 2, 1}, {"CA", 1, 2}, {"FL", 3, 3}}
+	got := d.RankChanges(2000, 2010)
+	if !slices.Equal(got, want) {
+		t.Fatalf("RankChanges(2000, 2010) = %v, want %v", got, want)
+	}
+	for i, moved := range []int{1, -1, 0} {
+		if got[i].Moved() != moved {
+			t.Errorf("%s moved %d, want %d", got[i].Name, got[i].Moved(), moved)
+		}
+	}
+	if got := d.RankChanges(1990, 2010); len(got) != 0 {
+		t.Errorf("RankChanges from a year without data = %v", got)
+	}
+}
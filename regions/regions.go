@@ -0,0 +1,252 @@
+// Package regions gives the canSee* role flags something to cover: a tree of
+// continents, countries and their states or provinces.
+//
+// A user with canSeeNorthAmerica may see the United States, so also every
+// state in it, which is what data keyed like the statePopulations map in
+// test.go needs:
+//
+//	visible := regions.Filter(regions.Default(), user.Roles, "US", statePopulations)
+//
+// The bundled dataset (regions.json) lists the larger countries of each
+// continent that has a role flag, with the subdivisions of some of them. Codes
+// follow ISO 3166: alpha-2 for countries and ISO 3166-2 (US-CA) for
+// subdivisions. Continents use lowercase names (europe, north-america) so
+// they can't clash with country codes: NA is Namibia, SA Saudi Arabia.
+package regions
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/raproid/go-training/roles"
+)
+
+// Kind is the level of a region in the tree.
+type Kind int
+
+const (
+	Continent Kind = iota
+	Country
+	Subdivision // a state, province or the like
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Continent:
+		return "continent"
+	case Country:
+		return "country"
+	case Subdivision:
+		return "subdivision"
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// Region is a node of the tree.
+type Region struct {
+	Code     string
+	Name     string
+	Kind     Kind
+	Role     roles.Role // the role that grants access; only set on continents
+	Parent   *Region
+	Children []*Region
+}
+
+// Short returns the code without the country prefix, so "CA" for "US-CA".
+func (r *Region) Short() string {
+	if r.Kind == Subdivision {
+		if _, short, ok := strings.Cut(r.Code, "-"); ok {
+			return short
+		}
+	}
+	return r.Code
+}
+
+// Continent returns the continent r belongs to, r itself for a continent.
+func (r *Region) Continent() *Region {
+	for r.Parent != nil {
+		r = r.Parent
+	}
+	return r
+}
+
+// Child returns the child of r with the given code, which may be the full
+// code or the short one, or nil.
+func (r *Region) Child(code string) *Region {
+	for _, c := range r.Children {
+		if strings.EqualFold(c.Code, code) || strings.EqualFold(c.Short(), code) {
+			return c
+		}
+	}
+	return nil
+}
+
+// Walk calls fn for r and every region below it, parents before children.
+func (r *Region) Walk(fn func(*Region)) {
+	fn(r)
+	for _, c := range r.Children {
+		c.Walk(fn)
+	}
+}
+
+func (r *Region) String() string {
+	return r.Name + " (" + r.Code + ")"
+}
+
+// World is a complete tree of regions.
+type World struct {
+	Continents []*Region
+	byCode     map[string]*Region
+}
+
+// file is the JSON layout of a dataset, as in regions.json.
+type file struct {
+	Continents []struct {
+		Code      string     `json:"code"`
+		Name      string     `json:"name"`
+		Role      roles.Role `json:"role"`
+		Countries []struct {
+			Code         string `json:"code"`
+			Name         string `json:"name"`
+			Subdivisions []struct {
+				Code string `json:"code"`
+				Name string `json:"name"`
+			} `json:"subdivisions"`
+		} `json:"countries"`
+	} `json:"continents"`
+}
+
+// Load reads a dataset in the format of regions.json. Codes must be unique,
+// and subdivision codes must start with the code of their country.
+func Load(r io.Reader) (*World, error) {
+	var f file
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, fmt.Errorf("regions: %v", err)
+	}
+
+	w := &World{byCode: map[string]*Region{}}
+	add := func(parent *Region, code, name string, kind Kind) (*Region, error) {
+		if code == "" || name == "" {
+			return nil, fmt.Errorf("regions: %s %q needs both a code and a name", kind, code+name)
+		}
+		if prev, ok := w.byCode[strings.ToUpper(code)]; ok {
+			return nil, fmt.Errorf("regions: code %s is used by both %s and %s", code, prev.Name, name)
+		}
+		reg := &Region{Code: code, Name: name, Kind: kind, Parent: parent}
+		if parent != nil {
+			parent.Children = append(parent.Children, reg)
+		}
+		w.byCode[strings.ToUpper(code)] = reg
+		return reg, nil
+	}
+
+	for _, c := range f.Continents {
+		cont, err := add(nil, c.Code, c.Name, Continent)
+		if err != nil {
+			return nil, err
+		}
+		cont.Role = c.Role
+		w.Continents = append(w.Continents, cont)
+		for _, cc := range c.Countries {
+			country, err := add(cont, cc.Code, cc.Name, Country)
+			if err != nil {
+				return nil, err
+			}
+			for _, s := range cc.Subdivisions {
+				if !strings.HasPrefix(s.Code, country.Code+"-") {
+					return nil, fmt.Errorf("regions: subdivision %s of %s doesn't start with %s-", s.Code, country.Name, country.Code)
+				}
+				if _, err := add(country, s.Code, s.Name, Subdivision); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return w, nil
+}
+
+//go:embed regions.json
+var bundled string
+
+var (
+	defaultOnce  sync.Once
+	defaultWorld *World
+)
+
+// Default returns the bundled dataset, parsed on first use.
+func Default() *World {
+	defaultOnce.Do(func() {
+		w, err := Load(strings.NewReader(bundled))
+		if err != nil {
+			panic(err) // regions.json is part of the package, so this is a bug
+		}
+		defaultWorld = w
+	})
+	return defaultWorld
+}
+
+// Lookup returns the region with the given full code, like "europe", "DE" or
+// "US-CA". Case doesn't matter.
+func (w *World) Lookup(code string) (*Region, bool) {
+	r, ok := w.byCode[strings.ToUpper(code)]
+	return r, ok
+}
+
+// Granted returns the continents the canSee* flags in r grant.
+func (w *World) Granted(r roles.Role) []*Region {
+	var list []*Region
+	for _, c := range w.Continents {
+		if c.Role != 0 && r.Has(c.Role) {
+			list = append(list, c)
+		}
+	}
+	return list
+}
+
+// Expand returns every region the flags in r grant: the continents, their
+// countries and the countries' subdivisions.
+func (w *World) Expand(r roles.Role) []*Region {
+	var list []*Region
+	for _, c := range w.Granted(r) {
+		c.Walk(func(reg *Region) { list = append(list, reg) })
+	}
+	return list
+}
+
+// CanSee reports whether the flags in r grant reg.
+func (w *World) CanSee(r roles.Role, reg *Region) bool {
+	c := reg.Continent()
+	return c.Role != 0 && r.Has(c.Role)
+}
+
+// Filter returns the entries of m that r may see. The keys are codes of
+// regions under scope, full or short ("US-CA" or "CA" for scope "US"); with
+// an empty scope they are full codes. Keys that don't name a region are
+// dropped, since nobody is known to be allowed to see them.
+func Filter[V any](w *World, r roles.Role, scope string, m map[string]V) map[string]V {
+	var parent *Region
+	if scope != "" {
+		var ok bool
+		if parent, ok = w.Lookup(scope); !ok {
+			return map[string]V{}
+		}
+	}
+
+	out := map[string]V{}
+	for key, v := range m {
+		var reg *Region
+		if parent != nil {
+			reg = parent.Child(key)
+		} else {
+			reg, _ = w.Lookup(key)
+		}
+		if reg != nil && w.CanSee(r, reg) {
+			out[key] = v
+		}
+	}
+	return out
+}
@@ -0,0 +1,182 @@
+package regions
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/raproid/go-training/roles"
+)
+
+const small = `{"continents": [
+	{"code": "europe", "name": "Europe", "role": "canSeeEurope", "countries": [
+		{"code": "DE", "name": "Germany", "subdivisions": [{"code": "DE-BY", "name": "Bavaria"}]},
+		{"code": "FR", "name": "France"}
+	]},
+	{"code": "north-america", "name": "North America", "role": ["canSeeNorthAmerica"], "countries": [
+		{"code": "US", "name": "United States", "subdivisions": [
+			{"code": "US-CA", "name": "California"},
+			{"code": "US-TX", "name": "Texas"}
+		]},
+		{"code": "CA", "name": "Canada", "subdivisions": [{"code": "CA-ON", "name": "Ontario"}]}
+	]},
+	{"code": "antarctica", "name": "Antarctica"}
+]}`
+
+func load(t *testing.T, src string) *World {
+	t.Helper()
+	w, err := Load(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func TestLoad(t *testing.T) {
+	w := load(t, small)
+	if len(w.Continents) != 3 {
+		t.Fatalf("%d continents, want 3", len(w.Continents))
+	}
+	tests := []struct {
+		code      string
+		kind      Kind
+		short     string
+		continent string
+		parent    string
+	}{
+		{"europe", Continent, "europe", "europe", ""},
+		{"de", Country, "DE", "europe", "europe"},
+		{"DE-BY", Subdivision, "BY", "europe", "DE"},
+		{"us-ca", Subdivision, "CA", "north-america", "US"},
+		{"CA", Country, "CA", "north-america", "north-america"},
+	}
+	for _, tt := range tests {
+		r, ok := w.Lookup(tt.code)
+		if !ok {
+			t.Errorf("Lookup(%q) found nothing", tt.code)
+			continue
+		}
+		parent := ""
+		if r.Parent != nil {
+			parent = r.Parent.Code
+		}
+		if r.Kind != tt.kind || r.Short() != tt.short || r.Continent().Code != tt.continent || parent != tt.parent {
+			t.Errorf("Lookup(%q) = %s, a %v with short code %s in %s under %q", tt.code, r, r.Kind, r.Short(), r.Continent().Code, parent)
+		}
+	}
+	if _, ok := w.Lookup("XX"); ok {
+		t.Error("Lookup(XX) found a region")
+	}
+	if europe, _ := w.Lookup("europe"); europe.Role != roles.CanSeeEurope {
+		t.Errorf("Europe has role %v", europe.Role)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+	}{
+		{"not JSON", `{"continents": [`, "regions: unexpected EOF"},
+		{"unknown role", `{"continents": [{"code": "europe", "name": "Europe", "role": "canSeeMars"}]}`, `roles: unknown role "canSeeMars"`},
+		{"no name", `{"continents": [{"code": "europe"}]}`, `regions: continent "europe" needs both a code and a name`},
+		{"no code", `{"continents": [{"code": "europe", "name": "Europe", "countries": [{"name": "France"}]}]}`, `regions: country "France" needs both a code and a name`},
+		{"duplicate code", `{"continents": [
+			{"code": "europe", "name": "Europe", "countries": [{"code": "FR", "name": "France"}]},
+			{"code": "africa", "name": "Africa", "countries": [{"code": "fr", "name": "Fraud"}]}
+		]}`, "regions: code fr is used by both France and Fraud"},
+		{"subdivision of another country", `{"continents": [{"code": "europe", "name": "Europe", "countries": [
+			{"code": "DE", "name": "Germany", "subdivisions": [{"code": "FR-75", "name": "Paris"}]}
+		]}]}`, "regions: subdivision FR-75 of Germany doesn't start with DE-"},
+		{"subdivision without a dash", `{"continents": [{"code": "europe", "name": "Europe", "countries": [
+			{"code": "DE", "name": "Germany", "subdivisions": [{"code": "DEBY", "name": "Bavaria"}]}
+		]}]}`, "regions: subdivision DEBY of Germany doesn't start with DE-"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(strings.NewReader(tt.src))
+			if err == nil {
+				t.Fatal("no error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %q, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpand(t *testing.T) {
+	w := load(t, small)
+	var codes []string
+	for _, r := range w.Expand(roles.IsAdmin | roles.CanSeeNorthAmerica) {
+		codes = append(codes, r.Code)
+	}
+	if want := []string{"north-america", "US", "US-CA", "US-TX", "CA", "CA-ON"}; !slices.Equal(codes, want) {
+		t.Errorf("Expand = %v, want %v", codes, want)
+	}
+	if got := w.Granted(roles.All); len(got) != 2 {
+		t.Errorf("all roles grant %v; a continent without a role isn't granted", got)
+	}
+	if got := w.Expand(roles.IsAdmin); len(got) != 0 {
+		t.Errorf("isAdmin alone grants %v", got)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	w := load(t, small)
+	states := map[string]int{"CA": 39250017, "TX": 27862596, "ON": 0, "US-CA": 1, "XX": 0}
+	countries := map[string]int{"US": 1, "CA": 2, "DE": 3, "DE-BY": 4, "ca-on": 5, "XX": 6}
+	tests := []struct {
+		name  string
+		role  roles.Role
+		scope string
+		m     map[string]int
+		want  []string
+	}{
+		{"states of a visible country", roles.CanSeeNorthAmerica, "US", states, []string{"CA", "TX", "US-CA"}},
+		{"states of an invisible country", roles.CanSeeEurope, "US", states, nil},
+		{"no roles", 0, "US", states, nil},
+		{"provinces, by short code", roles.CanSeeNorthAmerica, "CA", states, []string{"ON"}},
+		{"scope in another case", roles.CanSeeNorthAmerica, "us", states, []string{"CA", "TX", "US-CA"}},
+		{"unknown scope", roles.All, "XX", states, nil},
+		{"full codes", roles.CanSeeEurope, "", countries, []string{"DE", "DE-BY"}},
+		{"full codes, two continents", roles.CanSeeEurope | roles.CanSeeNorthAmerica, "", countries, []string{"CA", "DE", "DE-BY", "US", "ca-on"}},
+		{"continent scope", roles.CanSeeEurope, "europe", countries, []string{"DE"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := slices.Sorted(maps.Keys(Filter(w, tt.role, tt.scope, tt.m)))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Filter(%v, %q) = %v, want %v", tt.role, tt.scope, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefault(t *testing.T) {
+	w := Default()
+	if w != Default() {
+		t.Error("Default parses the dataset again")
+	}
+	for _, c := range w.Continents {
+		if c.Role == 0 {
+			t.Errorf("%s has no role", c)
+		}
+	}
+	// every canSee* flag that names a continent has one
+	for _, r := range []roles.Role{roles.CanSeeAfrica, roles.CanSeeAsia, roles.CanSeeEurope, roles.CanSeeNorthAmerica, roles.CanSeeSouthAmerica} {
+		if got := w.Granted(r); len(got) != 1 {
+			t.Errorf("%v grants %v, want one continent", r, got)
+		}
+	}
+	statePopulations := map[string]int{"CA": 39250017, "TX": 27862596, "FL": 20612439}
+	visible := Filter(w, roles.IsAdmin|roles.CanSeeFinance|roles.CanSeeEurope, "US", statePopulations)
+	if len(visible) != 0 {
+		t.Errorf("the lesson's user sees %v", visible)
+	}
+	visible = Filter(w, roles.CanSeeNorthAmerica, "US", statePopulations)
+	if fmt.Sprint(visible) != fmt.Sprint(statePopulations) {
+		t.Errorf("canSeeNorthAmerica sees %v, want every state", visible)
+	}
+}
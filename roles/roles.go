@@ -0,0 +1,143 @@
+// Package roles is the role bitmask from the bit shifting lesson as a type.
+//
+// test.go packs a user's roles into a byte:
+//
+//	const (
+//		isAdmin = 1 << iota
+//		isHeadquarters
+//		canSeeFinance
+//		...
+//	)
+//	var roles byte = isAdmin | canSeeFinance | canSeeEurope
+//
+// Role keeps that representation and adds names, so roles can be read from
+// configuration files and printed in error messages.
+package roles
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// Role is a set of roles, one bit each.
+type Role byte
+
+const (
+	IsAdmin Role = 1 << iota
+	IsHeadquarters
+	CanSeeFinance
+
+	CanSeeAfrica
+	CanSeeAsia
+	CanSeeEurope
+	CanSeeNorthAmerica
+	CanSeeSouthAmerica
+)
+
+// All holds every defined role.
+const All = IsAdmin | IsHeadquarters | CanSeeFinance |
+	CanSeeAfrica | CanSeeAsia | CanSeeEurope | CanSeeNorthAmerica | CanSeeSouthAmerica
+
+// names are indexed by bit number and match the constant names in test.go.
+var names = [...]string{
+	"isAdmin",
+	"isHeadquarters",
+	"canSeeFinance",
+	"canSeeAfrica",
+	"canSeeAsia",
+	"canSeeEurope",
+	"canSeeNorthAmerica",
+	"canSeeSouthAmerica",
+}
+
+// Has reports whether r includes every role in want, the want&roles == want
+// check from the lesson. An empty want is always satisfied.
+func (r Role) Has(want Role) bool {
+	return r&want == want
+}
+
+// Missing returns the roles of want that r doesn't have.
+func (r Role) Missing(want Role) Role {
+	return want &^ r
+}
+
+// Names returns the names of the roles in r, lowest bit first.
+func (r Role) Names() []string {
+	list := make([]string, 0, bits.OnesCount8(uint8(r)))
+	for i, name := range names {
+		if r&(1<<i) != 0 {
+			list = append(list, name)
+		}
+	}
+	return list
+}
+
+// String joins the role names with |, like the expression that builds the
+// set; the empty set is "none".
+func (r Role) String() string {
+	if r == 0 {
+		return "none"
+	}
+	return strings.Join(r.Names(), "|")
+}
+
+// Parse returns the role with the given name. Names are case-insensitive,
+// so "canSeeEurope" and "CanSeeEurope" both work.
+func Parse(name string) (Role, error) {
+	for i, n := range names {
+		if strings.EqualFold(n, name) {
+			return 1 << i, nil
+		}
+	}
+	return 0, fmt.Errorf("roles: unknown role %q", name)
+}
+
+// ParseList returns the union of the named roles. The names may be separated
+// by commas, | or spaces.
+func ParseList(s string) (Role, error) {
+	var r Role
+	for _, name := range strings.FieldsFunc(s, func(c rune) bool { return c == ',' || c == '|' || c == ' ' }) {
+		role, err := Parse(name)
+		if err != nil {
+			return 0, err
+		}
+		r |= role
+	}
+	return r, nil
+}
+
+// MarshalJSON encodes r as a list of role names.
+func (r Role) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.Names())
+}
+
+// UnmarshalJSON accepts a list of role names, a single string in the
+// ParseList format, or the raw bitmask as a number.
+func (r *Role) UnmarshalJSON(data []byte) error {
+	var list []string
+	if err := json.Unmarshal(data, &list); err == nil {
+		role, err := ParseList(strings.Join(list, ","))
+		if err != nil {
+			return err
+		}
+		*r = role
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		role, err := ParseList(s)
+		if err != nil {
+			return err
+		}
+		*r = role
+		return nil
+	}
+	var n uint8
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("roles: want a list of role names or a number, got %s", data)
+	}
+	*r = Role(n)
+	return nil
+}
@@ -0,0 +1,129 @@
+package roles
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestHas(t *testing.T) {
+	user := IsAdmin | CanSeeFinance | CanSeeEurope
+	tests := []struct {
+		want Role
+		has  bool
+	}{
+		{IsAdmin, true},
+		{IsAdmin | CanSeeEurope, true},
+		{IsAdmin | IsHeadquarters, false},
+		{CanSeeAsia, false},
+		{0, true},
+	}
+	for _, tt := range tests {
+		if got := user.Has(tt.want); got != tt.has {
+			t.Errorf("%v.Has(%v) = %v, want %v", user, tt.want, got, tt.has)
+		}
+	}
+	if got := user.Missing(IsAdmin | IsHeadquarters | CanSeeAsia); got != IsHeadquarters|CanSeeAsia {
+		t.Errorf("Missing = %v", got)
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		r    Role
+		want string
+	}{
+		{0, "none"},
+		{IsAdmin, "isAdmin"},
+		{CanSeeEurope | IsAdmin, "isAdmin|canSeeEurope"},
+		{All, "isAdmin|isHeadquarters|canSeeFinance|canSeeAfrica|canSeeAsia|canSeeEurope|canSeeNorthAmerica|canSeeSouthAmerica"},
+	}
+	for _, tt := range tests {
+		if got := tt.r.String(); got != tt.want {
+			t.Errorf("Role(%d).String() = %q, want %q", byte(tt.r), got, tt.want)
+		}
+	}
+}
+
+func TestParseList(t *testing.T) {
+	tests := []struct {
+		s    string
+		want Role
+	}{
+		{"", 0},
+		{"isAdmin", IsAdmin},
+		{"CanSeeEurope", CanSeeEurope},
+		{"ISADMIN", IsAdmin},
+		{"isAdmin,canSeeFinance", IsAdmin | CanSeeFinance},
+		{"isAdmin|canSeeFinance | canSeeEurope", IsAdmin | CanSeeFinance | CanSeeEurope},
+		{" isAdmin ,, isAdmin ", IsAdmin},
+		{All.String(), All},
+	}
+	for _, tt := range tests {
+		got, err := ParseList(tt.s)
+		if err != nil {
+			t.Errorf("ParseList(%q): %v", tt.s, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseList(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+
+	for _, s := range []string{"isRoot", "isAdmin,canSeeMars", "none", "isAdmin;canSeeAsia"} {
+		if got, err := ParseList(s); err == nil {
+			t.Errorf("ParseList(%q) = %v, want an error", s, got)
+		}
+	}
+	if _, err := ParseList("isAdmin,canSeeMars"); err == nil || err.Error() != `roles: unknown role "canSeeMars"` {
+		t.Errorf("error %v names the wrong role", err)
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		json string
+		want Role
+	}{
+		{`["isAdmin", "canSeeEurope"]`, IsAdmin | CanSeeEurope},
+		{`[]`, 0},
+		{`"isAdmin|canSeeFinance"`, IsAdmin | CanSeeFinance},
+		{`""`, 0},
+		{`37`, IsAdmin | CanSeeFinance | CanSeeEurope},
+		{`255`, All},
+	}
+	for _, tt := range tests {
+		var r Role
+		if err := json.Unmarshal([]byte(tt.json), &r); err != nil {
+			t.Errorf("Unmarshal(%s): %v", tt.json, err)
+			continue
+		}
+		if r != tt.want {
+			t.Errorf("Unmarshal(%s) = %v, want %v", tt.json, r, tt.want)
+		}
+	}
+
+	for _, data := range []string{`["canSeeMars"]`, `"canSeeMars"`, `256`, `-1`, `1.5`, `{"isAdmin": true}`, `[1, 2]`} {
+		r := CanSeeAsia
+		if err := json.Unmarshal([]byte(data), &r); err == nil {
+			t.Errorf("Unmarshal(%s) = %v, want an error", data, r)
+		} else if r != CanSeeAsia {
+			t.Errorf("the failed Unmarshal(%s) changed the role to %v", data, r)
+		}
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	for _, r := range []Role{0, IsAdmin, IsAdmin | CanSeeSouthAmerica, All} {
+		data, err := json.Marshal(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got Role
+		if err := json.Unmarshal(data, &got); err != nil || got != r {
+			t.Errorf("round trip of %v through %s gives %v, %v", r, data, got, err)
+		}
+	}
+	if data, _ := json.Marshal(IsAdmin | CanSeeEurope); string(data) != `["isAdmin","canSeeEurope"]` {
+		t.Errorf("Marshal = %s", data)
+	}
+}
@@ -0,0 +1,143 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/raproid/go-training/roles"
+)
+
+func TestAuthenticate(t *testing.T) {
+	tests := []struct {
+		header string
+		user   string
+		reason string
+	}{
+		{"", "", "missing bearer token"},
+		{"Bearer admin-token", "sofia", ""},
+		{"bearer  admin-token ", "sofia", ""},
+		{"Basic c29maWE6c2VjcmV0", "", "malformed Authorization header"},
+		{"Bearer", "", "malformed Authorization header"},
+		{"Bearer ", "", "malformed Authorization header"},
+		{"Bearer nobody-token", "", "unknown token"},
+	}
+	for _, tt := range tests {
+		var got authResult
+		h := authenticate(testUsers)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got, _ = r.Context().Value(authKey{}).(authResult)
+		}))
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.header != "" {
+			r.Header.Set("Authorization", tt.header)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), r)
+
+		if got.ok != (tt.user != "") || got.user.Name != tt.user {
+			t.Errorf("%q: user = %q (ok %v), want %q", tt.header, got.user.Name, got.ok, tt.user)
+		}
+		if !strings.HasPrefix(got.reason, tt.reason) || (tt.reason == "") != (got.reason == "") {
+			t.Errorf("%q: reason = %q, want %q", tt.header, got.reason, tt.reason)
+		}
+	}
+}
+
+func TestRequireRoles(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u, _ := userFrom(r.Context())
+		w.Write([]byte("welcome " + u.Name))
+	})
+	h := authenticate(testUsers)(requireRoles(roles.CanSeeFinance, ok))
+	tests := []struct {
+		name  string
+		token string
+		code  int
+		body  string
+	}{
+		{"no token", "", http.StatusUnauthorized, "unauthorized: missing bearer token"},
+		{"unknown token", "guess", http.StatusUnauthorized, "unauthorized: unknown token"},
+		{"missing role", "europe-token", http.StatusForbidden, "forbidden: anastasia needs canSeeFinance for /finance (has canSeeEurope)"},
+		{"granted", "admin-token", http.StatusOK, "welcome sofia"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := get(t, h, "/finance", tt.token)
+			if w.Code != tt.code || strings.TrimSpace(w.Body.String()) != tt.body {
+				t.Errorf("got %d %q, want %d %q", w.Code, strings.TrimSpace(w.Body.String()), tt.code, tt.body)
+			}
+			challenge := w.Header().Get("WWW-Authenticate")
+			if (tt.code == http.StatusUnauthorized) != (challenge != "") {
+				t.Errorf("WWW-Authenticate = %q on a %d", challenge, w.Code)
+			}
+		})
+	}
+}
+
+// TestRequireRolesWithoutAuthenticate checks that a route outside the
+// authenticate middleware is closed rather than open.
+func TestRequireRolesWithoutAuthenticate(t *testing.T) {
+	h := requireRoles(0, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the handler ran without authentication")
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(context.Background())
+	r.Header.Set("Authorization", "Bearer admin-token")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want 401", w.Code)
+	}
+}
+
+func TestProtectedRoutes(t *testing.T) {
+	h := newTestServer(t, nil, false)
+	tests := []struct {
+		path, token string
+		code        int
+		body        string
+	}{
+		{"/finance", "admin-token", http.StatusOK, "Finance report for sofia"},
+		{"/finance", "europe-token", http.StatusForbidden, "forbidden"},
+		{"/regions/europe", "europe-token", http.StatusOK, "Europe report for anastasia"},
+		{"/regions/europe", "admin-token", http.StatusForbidden, "needs canSeeEurope"},
+		{"/regions/europe", "", http.StatusUnauthorized, "unauthorized"},
+		{"/populations", "admin-token", http.StatusOK, "CA\t39250017\nFL\t20612439\nNY\t19745289\nTX\t27862596\n"},
+		{"/populations", "europe-token", http.StatusOK, "anastasia can't see any US state"},
+		{"/populations", "", http.StatusUnauthorized, "unauthorized"},
+	}
+	for _, tt := range tests {
+		w := get(t, h, tt.path, tt.token)
+		if w.Code != tt.code || !strings.Contains(w.Body.String(), tt.body) {
+			t.Errorf("%s with %q: got %d %q, want %d containing %q", tt.path, tt.token, w.Code, w.Body.String(), tt.code, tt.body)
+		}
+	}
+}
+
+func TestLoadUsers(t *testing.T) {
+	users, err := LoadUsers(filepath.Join("..", "users.example.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u := users["change-me-sofia"]; u.Name != "sofia" || u.Roles != roles.IsAdmin|roles.CanSeeFinance|roles.CanSeeEurope {
+		t.Errorf("sofia = %+v", u)
+	}
+
+	for _, tt := range []struct{ json, err string }{
+		{`[{"token": "t"}]`, "user #1 has no name"},
+		{`[{"name": "a"}]`, "user a has no token"},
+		{`[{"name": "a", "token": "t"}, {"name": "b", "token": "t"}]`, "users a and b have the same token"},
+		{`[{"name": "a", "token": "t", "roles": ["canFly"]}]`, "canFly"},
+		{`{}`, "cannot unmarshal"},
+	} {
+		path := filepath.Join(t.TempDir(), "users.json")
+		if err := os.WriteFile(path, []byte(tt.json), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadUsers(path); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: error = %v, want one containing %q", tt.json, err, tt.err)
+		}
+	}
+}
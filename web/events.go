@@ -0,0 +1,164 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/raproid/go-training/lessons"
+)
+
+// heartbeatInterval is how often a quiet event stream gets a comment, so that
+// proxies and browsers don't give up on it. It's a variable for the tests.
+var heartbeatInterval = 15 * time.Second
+
+// maxLiveRuns limits the tutorials running for event streams at once; every one
+// of them is a go build plus a process.
+const maxLiveRuns = 4
+
+// lessonEvents runs the tutorial and streams the output of one lesson as
+// server-sent events: an "output" event per line, then "done", or "failed" with
+// the error. Lessons depend on the ones before them, so the whole program runs,
+// but only up to the point where a later lesson prints something.
+func (p *Pages) lessonEvents(w http.ResponseWriter, r *http.Request) {
+	p.mu.RLock()
+	ls := p.lessons
+	p.mu.RUnlock()
+	name := r.PathValue("name")
+	idx := slices.IndexFunc(ls, func(l lessons.Lesson) bool { return l.Slug == name })
+	if idx < 0 {
+		http.Error(w, fmt.Sprintf("no lesson %q", name), http.StatusNotFound)
+		return
+	}
+	select {
+	case p.runs <- struct{}{}:
+		defer func() { <-p.runs }()
+	default:
+		w.Header().Set("Retry-After", "10")
+		http.Error(w, "too many lessons running, try again later", http.StatusServiceUnavailable)
+		return
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no") // keeps nginx from buffering the stream
+	w.WriteHeader(http.StatusOK)
+	rc := http.NewResponseController(w) // reaches the real writer through statusRecorder.Unwrap
+	rc.SetWriteDeadline(time.Time{})    // a lesson may take longer than the server's write timeout
+	if err := rc.Flush(); err != nil {
+		p.logger.Warn("event stream can't be flushed", slog.String("error", err.Error()))
+		return
+	}
+
+	// cancelled when the client goes away, which kills the program
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	lines := make(chan string)
+	var over atomic.Bool // a later lesson printed, so ours is complete
+	result := make(chan error, 1)
+	go func() {
+		result <- lessons.Run(ctx, p.src, ls, func(lesson int, line string) {
+			switch {
+			case lesson > idx:
+				over.Store(true)
+				cancel()
+			case lesson == idx:
+				select {
+				case lines <- line:
+				case <-ctx.Done():
+				}
+			}
+		})
+	}()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case line := <-lines:
+			writeEvent(w, "output", line)
+		case <-heartbeat.C:
+			io.WriteString(w, ": heartbeat\n\n")
+		case err := <-result: // Run only returns after every line was emitted
+			switch {
+			case err == nil || over.Load():
+				writeEvent(w, "done", "")
+			case r.Context().Err() != nil:
+				return // the client is gone, there's nobody to tell
+			default:
+				writeEvent(w, "failed", err.Error())
+			}
+			rc.Flush()
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return // the client is gone; the deferred cancel stops the program
+		}
+	}
+}
+
+// writeEvent writes one server-sent event; data with line breaks becomes
+// several data fields, which the browser joins with \n again.
+func writeEvent(w io.Writer, event, data string) {
+	fmt.Fprintf(w, "event: %s\n", event)
+	data = strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(data)
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(w, "data: %s\n", line)
+	}
+	io.WriteString(w, "\n")
+}
+
+// livePage shows the event stream of a lesson as it arrives; it's built in
+// rather than in the site templates since it's mostly the script. The stream is
+// closed on every end, or EventSource would reconnect and run the tutorial
+// again.
+var livePage = template.Must(template.New("live").Funcs(template.FuncMap{"lessonURL": links.Lesson, "staticURL": links.Static, "eventsURL": links.Events}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}} (live) · Go basics</title>
+<link rel="stylesheet" href="{{staticURL "style.css"}}">
+</head>
+<body>
+<nav><a href="{{lessonURL .Slug}}">← {{.Title}}</a></nav>
+<h1>{{.Title}} <small id="status">building…</small></h1>
+<pre class="output" id="output"></pre>
+<script>
+const out = document.getElementById("output"), status = document.getElementById("status");
+const events = new EventSource({{eventsURL .Slug}});
+events.addEventListener("output", e => { status.textContent = "running…"; out.textContent += e.data + "\n"; });
+events.addEventListener("done", () => { status.textContent = "done"; events.close(); });
+events.addEventListener("failed", e => { status.textContent = "failed: " + e.data; events.close(); });
+events.onerror = () => { status.textContent = "connection lost"; events.close(); };
+</script>
+</body>
+</html>
+`))
+
+func (p *Pages) live(w http.ResponseWriter, r *http.Request) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	slug := r.PathValue("slug")
+	i := slices.IndexFunc(p.lessons, func(l lessons.Lesson) bool { return l.Slug == slug })
+	if i < 0 {
+		http.Error(w, fmt.Sprintf("no lesson %q", slug), http.StatusNotFound)
+		return
+	}
+	var b bytes.Buffer
+	if err := livePage.Execute(&b, p.lessons[i]); err != nil {
+		p.fail(w, r, "live", err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	b.WriteTo(w)
+}
@@ -0,0 +1,228 @@
+package web
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/raproid/go-training/lessons/site"
+)
+
+func TestWriteEvent(t *testing.T) {
+	tests := []struct {
+		event, data, want string
+	}{
+		{"output", "hello", "event: output\ndata: hello\n\n"},
+		{"done", "", "event: done\ndata: \n\n"},
+		{"output", "two\nlines", "event: output\ndata: two\ndata: lines\n\n"},
+		{"output", "crlf\r\nand\rcr", "event: output\ndata: crlf\ndata: and\ndata: cr\n\n"},
+		{"output", "trailing\n", "event: output\ndata: trailing\ndata: \n\n"},
+		{"failed", "data: not a field", "event: failed\ndata: data: not a field\n\n"},
+	}
+	for _, tt := range tests {
+		var b strings.Builder
+		writeEvent(&b, tt.event, tt.data)
+		if b.String() != tt.want {
+			t.Errorf("writeEvent(%q, %q) = %q, want %q", tt.event, tt.data, b.String(), tt.want)
+		}
+	}
+}
+
+type event struct {
+	name, data string
+}
+
+// readEvents parses a server-sent event stream, returning the events and the
+// number of comments.
+func readEvents(t *testing.T, r io.Reader) (events []event, comments int) {
+	t.Helper()
+	var e event
+	var data []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case line == "":
+			if e.name != "" || data != nil {
+				e.data = strings.Join(data, "\n")
+				events = append(events, e)
+			}
+			e, data = event{}, nil
+		case strings.HasPrefix(line, ":"):
+			comments++
+		case strings.HasPrefix(line, "event: "):
+			e.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = append(data, strings.TrimPrefix(line, "data: "))
+		default:
+			t.Errorf("unexpected line %q in the stream", line)
+		}
+	}
+	return events, comments
+}
+
+func newEventServer(t *testing.T, tutorial string) (*httptest.Server, *Pages) {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	p, err := NewPages(logger, "tutorial.go", []byte(tutorial), "main", site.Files(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(NewHandler(logger, nil, p))
+	t.Cleanup(srv.Close)
+	return srv, p
+}
+
+func TestLessonEvents(t *testing.T) {
+	defer func(d time.Duration) { heartbeatInterval = d }(heartbeatInterval)
+	heartbeatInterval = 20 * time.Millisecond // the build alone takes longer than that
+
+	srv, p := newEventServer(t, testTutorial)
+	for _, tt := range []struct {
+		lesson string
+		want   []event
+	}{
+		{"greeting", []event{{"output", "hello"}, {"output", "world"}, {"done", ""}}},
+		{"farewell", []event{{"output", "bye"}, {"done", ""}}},
+	} {
+		resp, err := http.Get(srv.URL + "/events/lessons/" + tt.lesson)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		events, comments := readEvents(t, resp.Body)
+		resp.Body.Close()
+		if len(events) != len(tt.want) {
+			t.Errorf("%s: events = %v, want %v", tt.lesson, events, tt.want)
+		} else {
+			for i := range events {
+				if events[i] != tt.want[i] {
+					t.Errorf("%s: event %d = %v, want %v", tt.lesson, i, events[i], tt.want[i])
+				}
+			}
+		}
+		if comments == 0 {
+			t.Errorf("%s: no heartbeat comments", tt.lesson)
+		}
+	}
+	if len(p.runs) != 0 {
+		t.Errorf("%d run slots still taken", len(p.runs))
+	}
+}
+
+func TestLessonEventsFailed(t *testing.T) {
+	srv, _ := newEventServer(t, `package main
+
+import "os"
+
+func main() {
+	// exit: a lesson that fails
+	os.Stdout.WriteString("before\n")
+	os.Exit(3)
+}
+`)
+	resp, err := http.Get(srv.URL + "/events/lessons/exit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	events, _ := readEvents(t, resp.Body)
+	want := []event{{"output", "before"}, {"failed", "exit status 3"}}
+	if len(events) != 2 || events[0] != want[0] || events[1] != want[1] {
+		t.Errorf("events = %v, want %v", events, want)
+	}
+}
+
+func TestLessonEventsErrors(t *testing.T) {
+	srv, p := newEventServer(t, testTutorial)
+	resp, err := http.Get(srv.URL + "/events/lessons/nope")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown lesson: status %d, want 404", resp.StatusCode)
+	}
+
+	for range maxLiveRuns {
+		p.runs <- struct{}{}
+	}
+	resp, err = http.Get(srv.URL + "/events/lessons/greeting")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get("Retry-After") == "" {
+		t.Errorf("all slots taken: status %d, Retry-After %q, want 503 with Retry-After", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+}
+
+// TestLessonEventsDisconnect checks that a client going away stops the run
+// and frees its slot.
+func TestLessonEventsDisconnect(t *testing.T) {
+	srv, p := newEventServer(t, `package main
+
+import (
+	"fmt"
+	"time"
+)
+
+func main() {
+	// forever: a lesson that doesn't end
+	for {
+		fmt.Println("tick")
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+`)
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/events/lessons/forever", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := bufio.NewReader(resp.Body)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line == "data: tick\n" {
+			break
+		}
+	}
+	cancel()
+	resp.Body.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(p.runs) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the run is still going after the client left")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestLivePage(t *testing.T) {
+	h := newTestServer(t, nil, false)
+	w := get(t, h, "/lessons/greeting/live", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d", w.Code)
+	}
+	checkBody(t, w.Body.String(),
+		`<a href="/lessons/greeting">← greeting</a>`,
+		`new EventSource("/events/lessons/greeting")`,
+		"events.close()",
+	)
+	if w := get(t, h, "/lessons/nope/live", ""); w.Code != http.StatusNotFound {
+		t.Errorf("unknown lesson: status %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,206 @@
+package web
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// latencyBuckets are the upper bounds (in seconds) of the request latency
+// histogram, same as the Prometheus client defaults.
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// metrics collects what the /metrics endpoint exposes in the Prometheus text
+// format; only the standard library is used.
+type metrics struct {
+	mu       sync.Mutex
+	requests map[requestKey]uint64
+	latency  map[string]*histogram // by route
+
+	inFlight atomic.Int64
+	panics   atomic.Uint64
+	started  time.Time
+}
+
+type requestKey struct {
+	route string
+	code  int
+}
+
+type histogram struct {
+	counts []uint64 // one per bucket in latencyBuckets, not cumulative
+	sum    float64
+	count  uint64
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		requests: map[requestKey]uint64{},
+		latency:  map[string]*histogram{},
+		started:  time.Now(),
+	}
+}
+
+// instrument counts requests and measures their latency; the route label is the
+// mux pattern and not the raw path, so that /foo/1 and /foo/2 don't turn into
+// separate time series.
+func (m *metrics) instrument(mux *http.ServeMux) middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, route := mux.Handler(r)
+			if route == "" {
+				route = "unmatched"
+			}
+			m.inFlight.Add(1)
+			defer m.inFlight.Add(-1)
+
+			start := time.Now()
+			rec := &statusRecorder{ResponseWriter: w}
+			defer func() {
+				// deferred so that a panic that gets past recovery() is still counted
+				m.observe(route, rec.status(), time.Since(start))
+			}()
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
+
+func (m *metrics) observe(route string, code int, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[requestKey{route, code}]++
+	h, ok := m.latency[route]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(latencyBuckets))}
+		m.latency[route] = h
+	}
+	seconds := d.Seconds()
+	for i, le := range latencyBuckets {
+		if seconds <= le {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+// recordPanic is called by recovery() for every panic it turns into a 500.
+func (m *metrics) recordPanic() {
+	m.panics.Add(1)
+}
+
+// ServeHTTP renders all metrics in the Prometheus text exposition format
+// (version 0.0.4).
+func (m *metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.writeTo(w)
+}
+
+func (m *metrics) writeTo(w io.Writer) {
+	m.mu.Lock()
+	keys := make([]requestKey, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].route != keys[j].route {
+			return keys[i].route < keys[j].route
+		}
+		return keys[i].code < keys[j].code
+	})
+	writeHeader(w, "http_requests_total", "counter", "Total number of HTTP requests by route and status code.")
+	for _, k := range keys {
+		fmt.Fprintf(w, "http_requests_total{route=%s,code=\"%d\"} %d\n", quoteLabel(k.route), k.code, m.requests[k])
+	}
+
+	routes := make([]string, 0, len(m.latency))
+	for route := range m.latency {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+	writeHeader(w, "http_request_duration_seconds", "histogram", "HTTP request latency by route.")
+	for _, route := range routes {
+		h := m.latency[route]
+		label := quoteLabel(route)
+		var cumulative uint64
+		for i, le := range latencyBuckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(w, "http_request_duration_seconds_bucket{route=%s,le=\"%s\"} %d\n", label, formatFloat(le), cumulative)
+		}
+		fmt.Fprintf(w, "http_request_duration_seconds_bucket{route=%s,le=\"+Inf\"} %d\n", label, h.count)
+		fmt.Fprintf(w, "http_request_duration_seconds_sum{route=%s} %s\n", label, formatFloat(h.sum))
+		fmt.Fprintf(w, "http_request_duration_seconds_count{route=%s} %d\n", label, h.count)
+	}
+	m.mu.Unlock()
+
+	writeHeader(w, "http_requests_in_flight", "gauge", "Number of HTTP requests currently being served.")
+	fmt.Fprintf(w, "http_requests_in_flight %d\n", m.inFlight.Load())
+	writeHeader(w, "http_panics_recovered_total", "counter", "Number of handler panics turned into a 500 response.")
+	fmt.Fprintf(w, "http_panics_recovered_total %d\n", m.panics.Load())
+
+	writeRuntimeMetrics(w, m.started)
+}
+
+// writeRuntimeMetrics exposes the Go runtime stats under the names the official
+// Prometheus client uses.
+func writeRuntimeMetrics(w io.Writer, started time.Time) {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	writeHeader(w, "go_info", "gauge", "Information about the Go environment.")
+	fmt.Fprintf(w, "go_info{version=%s} 1\n", quoteLabel(runtime.Version()))
+	writeHeader(w, "go_goroutines", "gauge", "Number of goroutines that currently exist.")
+	fmt.Fprintf(w, "go_goroutines %d\n", runtime.NumGoroutine())
+	writeHeader(w, "go_sched_gomaxprocs_threads", "gauge", "The current runtime.GOMAXPROCS setting.")
+	fmt.Fprintf(w, "go_sched_gomaxprocs_threads %d\n", runtime.GOMAXPROCS(0))
+
+	gauges := []struct {
+		name, help string
+		value      uint64
+	}{
+		{"go_memstats_alloc_bytes", "Number of bytes allocated and still in use.", ms.Alloc},
+		{"go_memstats_sys_bytes", "Number of bytes obtained from the system.", ms.Sys},
+		{"go_memstats_heap_alloc_bytes", "Number of heap bytes allocated and still in use.", ms.HeapAlloc},
+		{"go_memstats_heap_inuse_bytes", "Number of heap bytes that are in use.", ms.HeapInuse},
+		{"go_memstats_heap_objects", "Number of allocated objects.", ms.HeapObjects},
+		{"go_memstats_stack_inuse_bytes", "Number of bytes in use by the stack allocator.", ms.StackInuse},
+		{"go_memstats_next_gc_bytes", "Number of heap bytes when the next garbage collection will take place.", ms.NextGC},
+	}
+	for _, g := range gauges {
+		writeHeader(w, g.name, "gauge", g.help)
+		fmt.Fprintf(w, "%s %d\n", g.name, g.value)
+	}
+
+	writeHeader(w, "go_memstats_alloc_bytes_total", "counter", "Total number of bytes allocated, even if freed.")
+	fmt.Fprintf(w, "go_memstats_alloc_bytes_total %d\n", ms.TotalAlloc)
+	writeHeader(w, "go_gc_cycles_total", "counter", "Number of completed GC cycles.")
+	fmt.Fprintf(w, "go_gc_cycles_total %d\n", ms.NumGC)
+	writeHeader(w, "go_gc_pause_seconds_total", "counter", "Total time spent in GC stop-the-world pauses.")
+	fmt.Fprintf(w, "go_gc_pause_seconds_total %s\n", formatFloat(time.Duration(ms.PauseTotalNs).Seconds()))
+	writeHeader(w, "process_start_time_seconds", "gauge", "Start time of the process since unix epoch in seconds.")
+	fmt.Fprintf(w, "process_start_time_seconds %s\n", formatFloat(float64(started.UnixNano())/1e9))
+}
+
+func writeHeader(w io.Writer, name, typ, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// labelEscaper escapes label values as the exposition format requires:
+// backslash, double quote and line feed.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quoteLabel(v string) string {
+	return `"` + labelEscaper.Replace(v) + `"`
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,112 @@
+package web
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sampleLine matches a sample of the Prometheus text format: a name, optional labels and a value.
+var sampleLine = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*(\{([a-zA-Z_][a-zA-Z0-9_]*="(\\.|[^"\\])*",?)*\})? (\+Inf|-Inf|NaN|[-+0-9.eE]+)$`)
+
+func TestMetricsFormat(t *testing.T) {
+	m := newMetrics()
+	m.observe("/finance", 200, 3*time.Millisecond)
+	m.observe("/finance", 200, 40*time.Millisecond)
+	m.observe("/finance", 403, 20*time.Second) // beyond the last bucket
+	m.observe(`GET /lessons/{slug}`, 200, time.Millisecond)
+	m.observe("a \"quoted\" \\ route\n", 404, time.Millisecond)
+	m.recordPanic()
+
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if ct := w.Header().Get("Content-Type"); ct != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	out := w.Body.String()
+
+	for _, want := range []string{
+		"# HELP http_requests_total Total number of HTTP requests by route and status code.\n# TYPE http_requests_total counter\n" +
+			`http_requests_total{route="/finance",code="200"} 2` + "\n" +
+			`http_requests_total{route="/finance",code="403"} 1` + "\n" +
+			`http_requests_total{route="GET /lessons/{slug}",code="200"} 1` + "\n" +
+			`http_requests_total{route="a \"quoted\" \\ route\n",code="404"} 1` + "\n",
+
+		"# TYPE http_request_duration_seconds histogram\n" +
+			`http_request_duration_seconds_bucket{route="/finance",le="0.005"} 1` + "\n" +
+			`http_request_duration_seconds_bucket{route="/finance",le="0.01"} 1` + "\n" +
+			`http_request_duration_seconds_bucket{route="/finance",le="0.025"} 1` + "\n" +
+			`http_request_duration_seconds_bucket{route="/finance",le="0.05"} 2` + "\n",
+		`http_request_duration_seconds_bucket{route="/finance",le="10"} 2` + "\n" +
+			`http_request_duration_seconds_bucket{route="/finance",le="+Inf"} 3` + "\n" +
+			`http_request_duration_seconds_sum{route="/finance"} 20.043` + "\n" +
+			`http_request_duration_seconds_count{route="/finance"} 3` + "\n",
+
+		"http_requests_in_flight 0\n",
+		"http_panics_recovered_total 1\n",
+		"# TYPE go_goroutines gauge\n",
+		"# TYPE go_memstats_alloc_bytes_total counter\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output doesn't contain\n%s", want)
+		}
+	}
+
+	// every sample must be valid and come after the TYPE line of its metric
+	typed := map[string]bool{}
+	sc := bufio.NewScanner(strings.NewReader(out))
+	for sc.Scan() {
+		line := sc.Text()
+		if name, ok := strings.CutPrefix(line, "# TYPE "); ok {
+			name, _, _ = strings.Cut(name, " ")
+			if typed[name] {
+				t.Errorf("%s is typed twice", name)
+			}
+			typed[name] = true
+			continue
+		}
+		if strings.HasPrefix(line, "# HELP ") {
+			continue
+		}
+		if !sampleLine.MatchString(line) {
+			t.Errorf("invalid sample line %q", line)
+			continue
+		}
+		name := line[:strings.IndexAny(line, "{ ")]
+		base := strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(name, "_bucket"), "_sum"), "_count")
+		if !typed[name] && !typed[base] {
+			t.Errorf("sample %s comes before its TYPE line", name)
+		}
+	}
+}
+
+// TestInstrumentRoute checks that requests are labelled with the mux pattern
+// and not the raw path.
+func TestInstrumentRoute(t *testing.T) {
+	m := newMetrics()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /lessons/{slug}", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "gone", http.StatusGone)
+	})
+	h := m.instrument(mux)(mux)
+	for _, path := range []string{"/lessons/a", "/lessons/b", "/nowhere"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	want := map[requestKey]uint64{
+		{"GET /lessons/{slug}", http.StatusGone}: 2,
+		{"unmatched", http.StatusNotFound}:       1,
+	}
+	if len(m.requests) != len(want) {
+		t.Errorf("requests = %v, want %v", m.requests, want)
+	}
+	for k, n := range want {
+		if m.requests[k] != n {
+			t.Errorf("requests[%v] = %d, want %d", k, m.requests[k], n)
+		}
+	}
+}
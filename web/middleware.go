@@ -0,0 +1,171 @@
+package web
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+// middleware wraps a handler with extra behaviour, e.g. logging or panic
+// recovery.
+type middleware func(http.Handler) http.Handler
+
+// chain applies the middlewares in the given order, so the first one is the
+// outermost: chain(h, a, b) == a(b(h)).
+func chain(h http.Handler, mws ...middleware) http.Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+type requestIDKey struct{}
+
+// requestIDHeader carries the request ID in both directions, so a caller (or a
+// proxy in front of us) may set its own.
+const requestIDHeader = "X-Request-ID"
+
+// requestID makes sure every request has an ID, stored in the request context
+// and echoed in the response.
+func requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if id == "" || len(id) > 64 {
+			id = newID()
+		}
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// requestIDFrom returns the ID set by the requestID middleware, or "" outside
+// of it.
+func requestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// newID returns a random 16-character hex ID.
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// accessLog writes one structured log record per request once the response is
+// done.
+func accessLog(logger *slog.Logger) middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &statusRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r)
+			logger.LogAttrs(r.Context(), slog.LevelInfo, "request",
+				slog.String("request_id", requestIDFrom(r.Context())),
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("remote", r.RemoteAddr),
+				slog.Int("status", rec.status()),
+				slog.Int64("bytes", rec.bytes),
+				slog.Duration("duration", time.Since(start)),
+			)
+		})
+	}
+}
+
+// timing reports how long the handler took until it started the response in the
+// Server-Timing header.
+func timing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		rec.beforeHeader = func() {
+			ms := float64(time.Since(start).Microseconds()) / 1000
+			w.Header().Add("Server-Timing", fmt.Sprintf("app;dur=%.3f", ms))
+		}
+		next.ServeHTTP(rec, r)
+	})
+}
+
+// recovery turns a panic in the handler (like the one in the tutorial's
+// panicker.go) into a 500 response carrying an error ID, which is logged
+// together with the stack trace, instead of dropping the connection; m counts
+// the recovered panics.
+func recovery(logger *slog.Logger, m *metrics) middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := &statusRecorder{ResponseWriter: w}
+			defer func() {
+				err := recover()
+				if err == nil {
+					return
+				}
+				if err == http.ErrAbortHandler {
+					panic(err) // the handler asked to abort the response, so let net/http do it
+				}
+				m.recordPanic()
+				errorID := newID()
+				logger.LogAttrs(r.Context(), slog.LevelError, "panic recovered",
+					slog.String("request_id", requestIDFrom(r.Context())),
+					slog.String("error_id", errorID),
+					slog.Any("error", err),
+					slog.String("stack", string(debug.Stack())),
+				)
+				if rec.wroteHeader {
+					return // too late for a 500, the client already got a status code
+				}
+				http.Error(rec, "internal server error (error ID: "+errorID+")", http.StatusInternalServerError)
+			}()
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
+
+// statusRecorder remembers the status code and body size written through it.
+type statusRecorder struct {
+	http.ResponseWriter
+	code         int
+	bytes        int64
+	wroteHeader  bool
+	beforeHeader func() // called once, right before the status code is sent
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if !r.wroteHeader {
+		r.wroteHeader = true
+		r.code = code
+		if r.beforeHeader != nil {
+			r.beforeHeader()
+		}
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to
+// flush.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// status returns the status code sent to the client; handlers that never write
+// anything get a 200.
+func (r *statusRecorder) status() int {
+	if !r.wroteHeader {
+		return http.StatusOK
+	}
+	return r.code
+}
@@ -0,0 +1,163 @@
+package web
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestChainOrder(t *testing.T) {
+	var calls []string
+	mw := func(name string) middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name+" before")
+				next.ServeHTTP(w, r)
+				calls = append(calls, name+" after")
+			})
+		}
+	}
+	h := chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler")
+	}), mw("a"), mw("b"), mw("c"))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	want := "a before, b before, c before, handler, c after, b after, a after"
+	if got := strings.Join(calls, ", "); got != want {
+		t.Errorf("calls = %s\nwant    %s", got, want)
+	}
+}
+
+var hexID = regexp.MustCompile(`^[0-9a-f]{16}$`)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		keep   bool // whether the ID from the request is used
+	}{
+		{"none", "", false},
+		{"given", "from-the-proxy", true},
+		{"too long", strings.Repeat("x", 65), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			h := requestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = requestIDFrom(r.Context())
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				r.Header.Set(requestIDHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			echoed := w.Header().Get(requestIDHeader)
+			if echoed != seen {
+				t.Errorf("response has ID %q, the handler saw %q", echoed, seen)
+			}
+			if tt.keep && echoed != tt.header {
+				t.Errorf("ID = %q, want %q", echoed, tt.header)
+			}
+			if !tt.keep && !hexID.MatchString(echoed) {
+				t.Errorf("ID = %q, want a new 16-character hex ID", echoed)
+			}
+		})
+	}
+	if id := requestIDFrom(httptest.NewRequest(http.MethodGet, "/", nil).Context()); id != "" {
+		t.Errorf("requestIDFrom outside the middleware = %q, want empty", id)
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	var logs bytes.Buffer
+	m := newMetrics()
+	h := chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("something bad happened")
+	}), requestID, recovery(slog.New(slog.NewJSONHandler(&logs, nil)), m))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", w.Code)
+	}
+	match := regexp.MustCompile(`\(error ID: ([0-9a-f]{16})\)`).FindStringSubmatch(w.Body.String())
+	if match == nil {
+		t.Fatalf("body %q has no error ID", w.Body.String())
+	}
+	records := logRecords(t, &logs)
+	if len(records) != 1 {
+		t.Fatalf("got %d log records, want 1", len(records))
+	}
+	rec := records[0]
+	if rec["error_id"] != match[1] {
+		t.Errorf("logged error_id = %v, the response says %s", rec["error_id"], match[1])
+	}
+	if rec["request_id"] != w.Header().Get(requestIDHeader) {
+		t.Errorf("logged request_id = %v, want %s", rec["request_id"], w.Header().Get(requestIDHeader))
+	}
+	if rec["error"] != "something bad happened" || !strings.Contains(rec["stack"].(string), "goroutine") {
+		t.Errorf("log record misses the panic value or the stack: %v", rec)
+	}
+	if n := m.panics.Load(); n != 1 {
+		t.Errorf("recovered panics = %d, want 1", n)
+	}
+}
+
+// TestRecoveryAfterHeader checks that a panic after the status code was sent
+// is logged, but doesn't try to send a 500 as well.
+func TestRecoveryAfterHeader(t *testing.T) {
+	var logs bytes.Buffer
+	h := recovery(slog.New(slog.NewJSONHandler(&logs, nil)), newMetrics())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("partial"))
+		panic("too late")
+	}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Code != http.StatusAccepted || w.Body.String() != "partial" {
+		t.Errorf("got %d %q, want the response as the handler left it", w.Code, w.Body.String())
+	}
+	if len(logRecords(t, &logs)) != 1 {
+		t.Errorf("the panic wasn't logged: %s", logs.String())
+	}
+}
+
+func TestRecoveryRepanicsErrAbortHandler(t *testing.T) {
+	var logs bytes.Buffer
+	m := newMetrics()
+	h := recovery(slog.New(slog.NewJSONHandler(&logs, nil)), m)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	w := httptest.NewRecorder()
+	defer func() {
+		if err := recover(); err != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler", err)
+		}
+		if w.Code != http.StatusOK || w.Body.Len() != 0 {
+			t.Errorf("got a %d response %q, want none", w.Code, w.Body.String())
+		}
+		if logs.Len() != 0 || m.panics.Load() != 0 {
+			t.Errorf("an abort was logged or counted as a panic: %s", logs.String())
+		}
+	}()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	t.Error("ServeHTTP returned, want the panic to continue")
+}
+
+func TestTiming(t *testing.T) {
+	h := timing(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if got := w.Header().Get("Server-Timing"); !regexp.MustCompile(`^app;dur=\d+\.\d{3}$`).MatchString(got) {
+		t.Errorf("Server-Timing = %q", got)
+	}
+}
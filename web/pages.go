@@ -0,0 +1,126 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/raproid/go-training/lessons"
+	"github.com/raproid/go-training/lessons/site"
+)
+
+// links are the routes of the pages, see NewHandler.
+var links = site.Links{
+	Index:  "/",
+	Lesson: func(slug string) string { return "/lessons/" + slug },
+	Static: func(name string) string { return "/static/" + name },
+	Live:   func(slug string) string { return "/lessons/" + slug + "/live" },
+	Events: func(slug string) string { return "/events/lessons/" + slug },
+}
+
+// Pages renders a tutorial as HTML: the contents on / and one page per lesson
+// with its narration, code and output.
+type Pages struct {
+	logger    *slog.Logger
+	src       []byte // the tutorial
+	source    string // its file name, without the directory
+	files     fs.FS  // laid out like site.Files
+	static    fs.FS
+	dev       bool            // parse the templates on every request, so edits show up on reload
+	templates *site.Templates // parsed once unless dev is set
+	runs      chan struct{}   // one slot per tutorial running for an event stream, see maxLiveRuns
+
+	mu       sync.RWMutex
+	lessons  []lessons.Lesson
+	captured bool // whether Output is filled in
+}
+
+// NewPages splits the body of the function funcName in the tutorial src, read
+// from filename, into lessons. The templates and static files come from
+// files, laid out like site.Files; with dev set, the templates are parsed
+// again on every request.
+func NewPages(logger *slog.Logger, filename string, src []byte, funcName string, files fs.FS, dev bool) (*Pages, error) {
+	ls, err := lessons.ParseFile(filename, src, funcName)
+	if err != nil {
+		return nil, err
+	}
+	p := &Pages{logger: logger, src: src, source: filepath.Base(filename), files: files, dev: dev, lessons: ls, runs: make(chan struct{}, maxLiveRuns)}
+	if p.static, err = fs.Sub(files, "static"); err != nil {
+		return nil, err
+	}
+	// parsing up front catches template errors at startup, in development mode too
+	if p.templates, err = site.Parse(files, links); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Capture runs the tutorial once to fill in the output of the lessons; until
+// it's done the lesson pages say so.
+func (p *Pages) Capture(ctx context.Context) {
+	p.mu.RLock()
+	ls := append([]lessons.Lesson(nil), p.lessons...)
+	p.mu.RUnlock()
+	if err := lessons.Capture(ctx, p.src, ls); err != nil {
+		// a lesson like the robots.txt fetch may fail; the output up to that point is still worth showing
+		p.logger.Warn("running the tutorial", slog.String("error", err.Error()))
+	}
+	p.mu.Lock()
+	p.lessons, p.captured = ls, true
+	p.mu.Unlock()
+}
+
+// render writes a page, or a clean 500 if the template fails.
+func (p *Pages) render(w http.ResponseWriter, r *http.Request, name string, data any) {
+	t := p.templates
+	if p.dev {
+		var err error
+		if t, err = site.Parse(p.files, links); err != nil {
+			p.fail(w, r, name, err)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := t.Render(w, name, data); err != nil {
+		p.fail(w, r, name, err)
+	}
+}
+
+func (p *Pages) fail(w http.ResponseWriter, r *http.Request, name string, err error) {
+	p.logger.LogAttrs(r.Context(), slog.LevelError, "rendering page",
+		slog.String("request_id", requestIDFrom(r.Context())),
+		slog.String("page", name),
+		slog.String("error", err.Error()),
+	)
+	http.Error(w, "internal server error", http.StatusInternalServerError)
+}
+
+func (p *Pages) index(w http.ResponseWriter, r *http.Request) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	p.render(w, r, "index", site.Index{Source: p.source, Lessons: p.lessons})
+}
+
+func (p *Pages) lesson(w http.ResponseWriter, r *http.Request) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	slug := r.PathValue("slug")
+	i := slices.IndexFunc(p.lessons, func(l lessons.Lesson) bool { return l.Slug == slug })
+	if i < 0 {
+		http.Error(w, fmt.Sprintf("no lesson %q", slug), http.StatusNotFound)
+		return
+	}
+	page := site.NewLesson(p.lessons, i, p.source)
+	page.Pending = !p.captured
+	p.render(w, r, "lesson", page)
+}
+
+// staticFiles serves the static directory under /static/.
+func (p *Pages) staticFiles() http.Handler {
+	return http.StripPrefix("/static/", http.FileServerFS(p.static))
+}
@@ -0,0 +1,94 @@
+package web
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/raproid/go-training/lessons/site"
+)
+
+func TestPages(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	p, err := NewPages(logger, "tutorial.go", []byte(testTutorial), "main", site.Files(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandler(logger, nil, p)
+
+	w := get(t, h, "/", "")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Fatalf("/: %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	checkBody(t, w.Body.String(),
+		"<code>tutorial.go</code>",
+		`<a href="/lessons/greeting">greeting</a>`,
+		`<a href="/lessons/farewell">farewell</a>`,
+	)
+
+	checkBody(t, get(t, h, "/lessons/greeting", "").Body.String(),
+		"<p>greeting: the first lesson</p>",
+		`<a href="/lessons/greeting/live">run live</a>`,
+		"still running",
+		`<a rel="next" href="/lessons/farewell">`,
+	)
+
+	p.Capture(context.Background())
+	page := get(t, h, "/lessons/greeting", "").Body.String()
+	checkBody(t, page, "<pre class=\"output\">hello\nworld\n</pre>")
+	if strings.Contains(page, "still running") {
+		t.Error("the page still says the tutorial is running after Capture")
+	}
+	checkBody(t, get(t, h, "/lessons/farewell", "").Body.String(), "<pre class=\"output\">bye\n</pre>")
+
+	if w := get(t, h, "/lessons/nope", ""); w.Code != http.StatusNotFound {
+		t.Errorf("unknown lesson: status %d, want 404", w.Code)
+	}
+	w = get(t, h, "/static/style.css", "")
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/css") {
+		t.Errorf("style.css: %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+}
+
+// TestPagesDev checks that development mode picks up edited templates and
+// turns a broken one into a 500.
+func TestPagesDev(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.CopyFS(dir, site.Files()); err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	p, err := NewPages(logger, "tutorial.go", []byte(testTutorial), "main", os.DirFS(dir), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandler(logger, nil, p)
+
+	index := filepath.Join(dir, "templates", "index.html")
+	if err := os.WriteFile(index, []byte(`{{define "content"}}<p>edited</p>{{end}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	checkBody(t, get(t, h, "/", "").Body.String(), "<p>edited</p>")
+
+	if err := os.WriteFile(index, []byte(`{{define "content"}}{{.Nope}}{{end}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	w := get(t, h, "/", "")
+	if w.Code != http.StatusInternalServerError || w.Body.String() != "internal server error\n" {
+		t.Errorf("broken template: got %d %q, want a clean 500", w.Code, w.Body.String())
+	}
+}
+
+func checkBody(t *testing.T, body string, want ...string) {
+	t.Helper()
+	for _, w := range want {
+		if !strings.Contains(body, w) {
+			t.Errorf("body doesn't contain %q:\n%s", w, body)
+		}
+	}
+}
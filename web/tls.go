@@ -0,0 +1,116 @@
+package web
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"time"
+)
+
+// tlsConfig returns the TLS settings for cfg, or nil if cfg asks for plain
+// HTTP.
+func tlsConfig(cfg Config, logger *slog.Logger) (*tls.Config, error) {
+	var cert tls.Certificate
+	switch {
+	case cfg.CertFile != "" || cfg.KeyFile != "":
+		if cfg.CertFile == "" || cfg.KeyFile == "" {
+			return nil, errors.New("TLS needs both a certificate and a key file")
+		}
+		// loaded here rather than by ListenAndServeTLS, so that a bad file stops the server before it listens anywhere
+		var err error
+		cert, err = tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+	case cfg.SelfSigned:
+		var err error
+		cert, err = selfSignedCert([]string{"localhost"}, []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}, 30*24*time.Hour)
+		if err != nil {
+			return nil, err
+		}
+		// browsers will warn about the certificate; the fingerprint lets you check it's really ours before clicking through
+		sum := sha256.Sum256(cert.Certificate[0])
+		logger.Warn("serving a self-signed certificate, for local development only",
+			slog.String("sha256", hex.EncodeToString(sum[:])),
+			slog.Time("expires", cert.Leaf.NotAfter),
+		)
+	default:
+		return nil, nil
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// selfSignedCert generates a certificate for the given names and addresses that
+// is signed by its own key; it only lives in memory.
+func selfSignedCert(dnsNames []string, ips []net.IP, validFor time.Duration) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"go-training"}, CommonName: "go-training development server"},
+		NotBefore:             now.Add(-time.Hour), // some slack for clocks that are a bit behind
+		NotAfter:              now.Add(validFor),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              dnsNames,
+		IPAddresses:           ips,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, nil
+}
+
+// redirectToHTTPS sends every plain HTTP request to the same host and path on
+// the HTTPS port; the port is left out of the URL when it's 443.
+func redirectToHTTPS(tlsAddr string) http.Handler {
+	_, port, err := net.SplitHostPort(tlsAddr)
+	if err != nil || port == "443" {
+		port = ""
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if host == "" {
+			http.Error(w, "bad request: no Host header", http.StatusBadRequest)
+			return
+		}
+		if port != "" {
+			host = net.JoinHostPort(host, port)
+		} else if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
+			host = "[" + host + "]" // SplitHostPort removed the brackets of an IPv6 address
+		}
+		u := *r.URL
+		u.Scheme, u.Host = "https", host
+		// temporary, because browsers cache permanent redirects and a development server switches between HTTP and HTTPS a lot
+		http.Redirect(w, r, u.String(), http.StatusTemporaryRedirect)
+	})
+}
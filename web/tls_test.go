@@ -0,0 +1,123 @@
+package web
+
+import (
+	"crypto/ecdsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRedirectToHTTPS(t *testing.T) {
+	tests := []struct {
+		tlsAddr, url, location string
+	}{
+		{":8443", "http://localhost:8080/finance?x=1", "https://localhost:8443/finance?x=1"},
+		{":443", "http://localhost:8080/finance?x=1", "https://localhost/finance?x=1"},
+		{"127.0.0.1:8443", "http://127.0.0.1:8080/", "https://127.0.0.1:8443/"},
+		{":443", "http://127.0.0.1/", "https://127.0.0.1/"},
+		{":8443", "http://[::1]:8080/a/b", "https://[::1]:8443/a/b"},
+		{":443", "http://[::1]:8080/a/b", "https://[::1]/a/b"},
+		{":8443", "http://example.com/", "https://example.com:8443/"},
+		{"not an address", "http://example.com:80/", "https://example.com/"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		redirectToHTTPS(tt.tlsAddr).ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.url, nil))
+		if w.Code != http.StatusTemporaryRedirect || w.Header().Get("Location") != tt.location {
+			t.Errorf("%s to %s: got %d to %q, want 307 to %q", tt.url, tt.tlsAddr, w.Code, w.Header().Get("Location"), tt.location)
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+	r.Host = ""
+	w := httptest.NewRecorder()
+	redirectToHTTPS(":443").ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("without a Host: status = %d, want 400", w.Code)
+	}
+}
+
+func TestSelfSignedCert(t *testing.T) {
+	cfg, err := tlsConfig(Config{SelfSigned: true}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf := cfg.Certificates[0].Leaf
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	for _, name := range []string{"localhost", "127.0.0.1", "::1"} {
+		if _, err := leaf.Verify(x509.VerifyOptions{DNSName: name, Roots: pool}); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{DNSName: "example.com", Roots: pool}); err == nil {
+		t.Error("the certificate is valid for example.com")
+	}
+	if cfg.MinVersion != tls.VersionTLS12 {
+		t.Errorf("MinVersion = %x, want TLS 1.2", cfg.MinVersion)
+	}
+
+	// a real handshake over 127.0.0.1, trusting only that certificate
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("secure"))
+	}))
+	srv.TLS = cfg
+	srv.StartTLS()
+	defer srv.Close()
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if body, _ := io.ReadAll(resp.Body); string(body) != "secure" {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestTLSConfigFromFiles(t *testing.T) {
+	cert, err := selfSignedCert([]string{"localhost"}, []net.IP{net.IPv4(127, 0, 0, 1)}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0o644)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg, err := tlsConfig(Config{CertFile: certFile, KeyFile: keyFile}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Certificates) != 1 || string(cfg.Certificates[0].Certificate[0]) != string(cert.Certificate[0]) {
+		t.Error("the configuration doesn't hold the certificate from the file")
+	}
+
+	for _, c := range []Config{
+		{CertFile: certFile},
+		{KeyFile: keyFile},
+		{CertFile: keyFile, KeyFile: certFile},
+		{CertFile: filepath.Join(dir, "missing.pem"), KeyFile: keyFile},
+	} {
+		if _, err := tlsConfig(c, logger); err == nil {
+			t.Errorf("%+v: no error", c)
+		}
+	}
+	if cfg, err := tlsConfig(Config{}, logger); cfg != nil || err != nil {
+		t.Errorf("plain HTTP: got %v, %v, want no TLS configuration", cfg, err)
+	}
+}
@@ -0,0 +1,152 @@
+// Package web serves the tutorial over HTTP: the lessons as HTML pages, their
+// output streamed live as server-sent events, reports guarded by the role
+// flags of the bit shifting lesson and metrics in the Prometheus format, all
+// behind a middleware chain that adds request IDs, logging and panic
+// recovery. Command webserver runs it.
+package web
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+
+	"github.com/raproid/go-training/lessons/site"
+	"github.com/raproid/go-training/regions"
+	"github.com/raproid/go-training/roles"
+)
+
+// Config says where the server listens; the zero TLS fields mean plain HTTP
+// only.
+type Config struct {
+	Addr       string // plain HTTP; with TLS on it only redirects to HTTPS, and "" turns it off
+	TLSAddr    string
+	CertFile   string // certificate and key in PEM, e.g. from Let's Encrypt or mkcert
+	KeyFile    string
+	SelfSigned bool // generate a certificate for localhost at startup instead of reading one
+	Dev        bool // reload templates and static files from disk on every request, and serve /panic
+}
+
+// Flags registers the command-line flags for the fields of c, with its current
+// values as defaults.
+func (c *Config) Flags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Addr, "addr", c.Addr, "address for plain HTTP; with TLS it redirects to HTTPS, empty turns it off")
+	fs.StringVar(&c.TLSAddr, "tls-addr", c.TLSAddr, "address for HTTPS")
+	fs.StringVar(&c.CertFile, "tls-cert", c.CertFile, "TLS certificate file (PEM)")
+	fs.StringVar(&c.KeyFile, "tls-key", c.KeyFile, "TLS key file (PEM)")
+	fs.BoolVar(&c.SelfSigned, "tls-self-signed", c.SelfSigned, "serve HTTPS with a self-signed certificate for localhost (development only)")
+	fs.BoolVar(&c.Dev, "dev", c.Dev, "read templates and static files from ./"+site.Dir+" on every request instead of the built-in copies, and serve /panic to try out panic recovery")
+}
+
+// Serve runs the server until one of its listeners fails: plain HTTP only, or
+// HTTPS plus a redirect from HTTP.
+func Serve(cfg Config, logger *slog.Logger, h http.Handler) error {
+	tc, err := tlsConfig(cfg, logger)
+	if err != nil {
+		return err
+	}
+	if tc == nil {
+		logger.Info("serving HTTP", slog.String("addr", cfg.Addr))
+		return http.ListenAndServe(cfg.Addr, h)
+	}
+
+	errs := make(chan error, 2)
+	go func() {
+		srv := &http.Server{Addr: cfg.TLSAddr, Handler: h, TLSConfig: tc}
+		errs <- srv.ListenAndServeTLS("", "") // the certificate is in TLSConfig already
+	}()
+	if cfg.Addr != "" {
+		go func() {
+			errs <- http.ListenAndServe(cfg.Addr, redirectToHTTPS(cfg.TLSAddr))
+		}()
+	}
+	logger.Info("serving HTTPS", slog.String("addr", cfg.TLSAddr), slog.String("redirect_from", cfg.Addr))
+	return <-errs
+}
+
+// route is a protected endpoint together with the roles a caller needs for it.
+type route struct {
+	pattern string
+	need    roles.Role
+	handler http.HandlerFunc
+}
+
+// NewHandler sets up the routes and wraps them in the middleware chain.
+// Requests are authenticated against users, which is keyed by token.
+func NewHandler(logger *slog.Logger, users map[string]User, p *Pages) http.Handler {
+	m := newMetrics()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", p.index) // only / itself, anything else unknown is a 404
+	mux.HandleFunc("GET /lessons/{slug}", p.lesson)
+	mux.HandleFunc("GET /lessons/{slug}/live", p.live)
+	mux.HandleFunc("GET /events/lessons/{name}", p.lessonEvents)
+	mux.Handle("GET /static/", p.staticFiles())
+	if p.dev {
+		// the panicker pattern, for trying out recovery(); anyone could call it, so it's not on in production
+		mux.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) {
+			panic("panicking") // recovery() turns it into a 500 instead of killing the connection
+		})
+	}
+	mux.Handle("/metrics", m)
+
+	for _, rt := range []route{
+		{"/finance", roles.CanSeeFinance, financeReport},
+		{"/populations", 0, populationReport}, // any signed-in user, filtered by their region flags
+	} {
+		mux.Handle(rt.pattern, requireRoles(rt.need, rt.handler))
+	}
+	// one route per continent, each guarded by its canSee* flag
+	for _, c := range regions.Default().Continents {
+		mux.Handle("/regions/"+c.Code, requireRoles(c.Role, regionReport(c)))
+	}
+
+	// the order matters: request IDs first so that everything below can log them, recovery below them so that the log and the metrics see the 500, authentication innermost since only the routes need it
+	return chain(mux,
+		requestID,
+		accessLog(logger),
+		m.instrument(mux),
+		timing,
+		recovery(logger, m),
+		authenticate(users),
+	)
+}
+
+func financeReport(w http.ResponseWriter, r *http.Request) {
+	u, _ := userFrom(r.Context())
+	fmt.Fprintf(w, "Finance report for %s\n", u.Name)
+}
+
+// regionReport lists the countries of a continent.
+func regionReport(continent *regions.Region) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := userFrom(r.Context())
+		fmt.Fprintf(w, "%s report for %s\n", continent.Name, u.Name)
+		for _, country := range continent.Children {
+			fmt.Fprintf(w, "%s\t%s\n", country.Code, country.Name)
+		}
+	}
+}
+
+// usStatePopulations is the statePopulations map from the maps lesson.
+var usStatePopulations = map[string]int{
+	"CA": 39250017,
+	"TX": 27862596,
+	"FL": 20612439,
+	"NY": 19745289,
+}
+
+// populationReport shows the states the caller's region flags cover, sorted by
+// code.
+func populationReport(w http.ResponseWriter, r *http.Request) {
+	u, _ := userFrom(r.Context())
+	visible := regions.Filter(regions.Default(), u.Roles, "US", usStatePopulations)
+	if len(visible) == 0 {
+		fmt.Fprintf(w, "%s can't see any US state (has %s)\n", u.Name, u.Roles)
+		return
+	}
+	for _, state := range slices.Sorted(maps.Keys(visible)) {
+		fmt.Fprintf(w, "%s\t%d\n", state, visible[state])
+	}
+}
@@ -0,0 +1,143 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/raproid/go-training/lessons/site"
+	"github.com/raproid/go-training/roles"
+)
+
+// testTutorial is a tutorial of two lessons, small enough to build and run in a test.
+const testTutorial = `package main
+
+import "fmt"
+
+func main() {
+	// greeting: the first lesson
+	fmt.Println("hello")
+	fmt.Println("world")
+
+	// farewell: the second lesson
+	fmt.Println("bye")
+}
+`
+
+var testUsers = map[string]User{
+	"admin-token":  {Name: "sofia", Token: "admin-token", Roles: roles.IsAdmin | roles.CanSeeFinance | roles.CanSeeNorthAmerica},
+	"europe-token": {Name: "anastasia", Token: "europe-token", Roles: roles.CanSeeEurope},
+}
+
+// newTestServer returns the full handler, with the access log going to logs if
+// it isn't nil and in development mode if dev is set.
+func newTestServer(t *testing.T, logs io.Writer, dev bool) http.Handler {
+	t.Helper()
+	if logs == nil {
+		logs = io.Discard
+	}
+	logger := slog.New(slog.NewJSONHandler(logs, nil))
+	p, err := NewPages(logger, "tutorial.go", []byte(testTutorial), "main", site.Files(), dev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewHandler(logger, testUsers, p)
+}
+
+func get(t *testing.T, h http.Handler, path, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+// logRecords decodes the JSON log lines written by slog.
+func logRecords(t *testing.T, logs *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for line := range strings.SplitSeq(strings.TrimSpace(logs.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var rec map[string]any
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("log line %q: %v", line, err)
+		}
+		records = append(records, rec)
+	}
+	return records
+}
+
+func TestPanicRouteOnlyInDev(t *testing.T) {
+	if w := get(t, newTestServer(t, nil, false), "/panic", ""); w.Code != http.StatusNotFound {
+		t.Errorf("/panic without -dev: status = %d, want 404", w.Code)
+	}
+}
+
+// TestPanicRoute checks the middleware order on a real route: the panic is
+// recovered below the access log, the metrics and the timing, so all of them
+// see the 500, and the request ID set outermost is on the response and in
+// both log records.
+func TestPanicRoute(t *testing.T) {
+	var logs bytes.Buffer
+	h := newTestServer(t, &logs, true)
+	r := httptest.NewRequest(http.MethodGet, "/panic", nil)
+	r.Header.Set(requestIDHeader, "req-1")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", w.Code)
+	}
+	if got := w.Header().Get(requestIDHeader); got != "req-1" {
+		t.Errorf("%s = %q, want req-1", requestIDHeader, got)
+	}
+	if w.Header().Get("Server-Timing") == "" {
+		t.Error("no Server-Timing header on the 500")
+	}
+
+	var panicked, request map[string]any
+	for _, rec := range logRecords(t, &logs) {
+		switch rec["msg"] {
+		case "panic recovered":
+			panicked = rec
+		case "request":
+			request = rec
+		}
+	}
+	if panicked == nil || request == nil {
+		t.Fatalf("want a panic and a request log record, got %s", logs.String())
+	}
+	if panicked["request_id"] != "req-1" || request["request_id"] != "req-1" {
+		t.Errorf("request IDs in the log = %v and %v, want req-1", panicked["request_id"], request["request_id"])
+	}
+	if request["status"] != float64(500) {
+		t.Errorf("access log status = %v, want 500", request["status"])
+	}
+
+	metrics := get(t, h, "/metrics", "").Body.String()
+	for _, want := range []string{
+		`http_requests_total{route="/panic",code="500"} 1`,
+		"http_panics_recovered_total 1",
+	} {
+		if !strings.Contains(metrics, want) {
+			t.Errorf("metrics don't contain %q", want)
+		}
+	}
+}
+
+func TestUnknownPath(t *testing.T) {
+	h := newTestServer(t, nil, false)
+	if w := get(t, h, "/no/such/page", ""); w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", w.Code)
+	}
+}